@@ -66,6 +66,10 @@ func (w *ResponseWriter) Reset() {
 // set so far, 0 is returned.
 func (w *ResponseWriter) StatusCode() int { return w.statusCode }
 
+// Body returns the body data written to w so far. The returned slice is only
+// valid until the next write to w.
+func (w *ResponseWriter) Body() []byte { return w.body.Bytes() }
+
 // Methods implemented to satisfy http.ResponseWriter
 
 func (w *ResponseWriter) Header() http.Header {
@@ -39,3 +39,75 @@ func TestBufferedResponse(t *testing.T) {
 		hello, world`, is.DedentLines, func(s string) string { return strings.ReplaceAll(s, "\r", "") }),
 	)
 }
+
+func TestBufferedResponse_Body(t *testing.T) {
+	var br bufferedresponse.ResponseWriter
+
+	io.WriteString(&br, "hello, ")
+	io.WriteString(&br, "world")
+
+	expect.That(t, is.EqualTo(string(br.Body()), "hello, world"))
+}
+
+func TestSpillingResponseWriter(t *testing.T) {
+	t.Run("belowThreshold", func(t *testing.T) {
+		rw := httptest.NewRecorder()
+		w := bufferedresponse.NewSpillingResponseWriter(rw, 16)
+
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusCreated)
+		io.WriteString(w, "hello")
+
+		expect.That(t,
+			is.EqualTo(w.Spilled(), false),
+			is.EqualTo(rw.Body.Len(), 0),
+		)
+
+		w.Reset()
+		io.WriteString(w, "world")
+		err := w.Finish()
+
+		expect.That(t,
+			is.NoError(err),
+			is.EqualTo(rw.Result().StatusCode, http.StatusOK),
+			is.EqualTo(rw.Header().Get("Content-Type"), ""),
+			is.EqualTo(rw.Body.String(), "world"),
+		)
+	})
+
+	t.Run("exceedsThreshold", func(t *testing.T) {
+		rw := httptest.NewRecorder()
+		w := bufferedresponse.NewSpillingResponseWriter(rw, 8)
+
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusCreated)
+		io.WriteString(w, "hello")
+		io.WriteString(w, ", world")
+
+		expect.That(t,
+			is.EqualTo(w.Spilled(), true),
+			is.EqualTo(w.StatusCode(), http.StatusCreated),
+			is.EqualTo(rw.Result().StatusCode, http.StatusCreated),
+			is.EqualTo(rw.Header().Get("Content-Type"), "text/plain"),
+			is.EqualTo(rw.Body.String(), "hello, world"),
+		)
+
+		err := w.Finish()
+		expect.That(t, is.NoError(err))
+	})
+
+	t.Run("flush", func(t *testing.T) {
+		rw := httptest.NewRecorder()
+		w := bufferedresponse.NewSpillingResponseWriter(rw, 1024)
+
+		io.WriteString(w, "data: 1\n\n")
+		err := http.NewResponseController(w).Flush()
+
+		expect.That(t,
+			is.NoError(err),
+			is.EqualTo(w.Spilled(), true),
+			is.EqualTo(rw.Flushed, true),
+			is.EqualTo(rw.Body.String(), "data: 1\n\n"),
+		)
+	})
+}
@@ -0,0 +1,127 @@
+package bufferedresponse
+
+import (
+	"io"
+	"net/http"
+)
+
+// SpillingResponseWriter implements [http.ResponseWriter] by buffering the
+// response in memory up to a threshold. Once the buffered body exceeds the
+// threshold or Flush is called, the buffered response is written to the
+// target [http.ResponseWriter] and all further data is written directly to
+// target ("spilled"). Up until then, the response can be discarded using
+// Reset.
+type SpillingResponseWriter struct {
+	buf       ResponseWriter
+	target    http.ResponseWriter
+	threshold int
+	spilled   bool
+}
+
+// NewSpillingResponseWriter creates a new SpillingResponseWriter writing to
+// target once more than threshold bytes have been written.
+func NewSpillingResponseWriter(target http.ResponseWriter, threshold int) *SpillingResponseWriter {
+	return &SpillingResponseWriter{
+		target:    target,
+		threshold: threshold,
+	}
+}
+
+// Spilled reports whether w has written data to its target. Once spilled the
+// response can no longer be discarded.
+func (w *SpillingResponseWriter) Spilled() bool { return w.spilled }
+
+// Reset resets w to an empty state. Calling Reset after w has been spilled
+// causes a panic.
+func (w *SpillingResponseWriter) Reset() {
+	if w.spilled {
+		panic("bufferedresponse: Reset called after response has been spilled")
+	}
+	w.buf.Reset()
+}
+
+// StatusCode returns the status code set for w. If no status code has been
+// set so far, 0 is returned.
+func (w *SpillingResponseWriter) StatusCode() int { return w.buf.StatusCode() }
+
+// Finish writes any buffered data to the target. It must be called after the
+// response has been completed. Finish returns any error returned from writing
+// to the target.
+func (w *SpillingResponseWriter) Finish() error {
+	if w.spilled {
+		return nil
+	}
+	return w.spill()
+}
+
+// spill writes the buffered response to the target and marks w as spilled.
+func (w *SpillingResponseWriter) spill() error {
+	w.spilled = true
+	return w.buf.WriteTo(w.target)
+}
+
+// Methods implemented to satisfy http.ResponseWriter
+
+func (w *SpillingResponseWriter) Header() http.Header {
+	if w.spilled {
+		return w.target.Header()
+	}
+	return w.buf.Header()
+}
+
+func (w *SpillingResponseWriter) Write(buf []byte) (int, error) {
+	if w.spilled {
+		return w.target.Write(buf)
+	}
+
+	if len(w.buf.Body())+len(buf) <= w.threshold {
+		return w.buf.Write(buf)
+	}
+
+	if err := w.spill(); err != nil {
+		return 0, err
+	}
+
+	return w.target.Write(buf)
+}
+
+func (w *SpillingResponseWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// ReadFrom reads data from r until EOF and writes it to w honoring the
+// threshold.
+func (w *SpillingResponseWriter) ReadFrom(r io.Reader) (int64, error) {
+	return io.Copy(struct{ io.Writer }{w}, r)
+}
+
+func (w *SpillingResponseWriter) WriteHeader(statusCode int) {
+	if w.spilled {
+		return
+	}
+	w.buf.WriteHeader(statusCode)
+}
+
+// Flush spills w and flushes the target, if it supports flushing. It satisfies
+// [http.Flusher].
+func (w *SpillingResponseWriter) Flush() {
+	w.FlushError()
+}
+
+// FlushError spills w and flushes the target. It returns any error that
+// occured. It is used by [http.ResponseController].
+func (w *SpillingResponseWriter) FlushError() error {
+	if !w.spilled {
+		if err := w.spill(); err != nil {
+			return err
+		}
+	}
+
+	return http.NewResponseController(w.target).Flush()
+}
+
+// Unwrap returns the target [http.ResponseWriter] to be used with
+// [http.ResponseController].
+func (w *SpillingResponseWriter) Unwrap() http.ResponseWriter {
+	return w.target
+}
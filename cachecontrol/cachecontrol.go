@@ -0,0 +1,230 @@
+// Package cachecontrol provides a HTTP middleware that applies caching
+// policies to responses based on the request's path and consolidates the Vary
+// header declared by handlers and other middlewares.
+package cachecontrol
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/halimath/httputils"
+	"github.com/halimath/httputils/response"
+)
+
+// Policy defines the caching policy applied to responses for a set of paths.
+type Policy struct {
+	// Path defines the policy's pattern and must be given. The pattern uses the
+	// same syntax as patterns registered with [http.ServeMux], i.e. /static/
+	// matches all paths starting with /static/. If multiple policies match a
+	// request, the most specific one is used as defined by [http.ServeMux].
+	Path string
+
+	// CacheControl defines the directives sent with the Cache-Control header.
+	// Use the zero value to send no Cache-Control header but still add Vary.
+	CacheControl response.CacheControl
+
+	// Vary lists request headers that select the representation in addition
+	// to those declared by handlers and other middlewares.
+	Vary []string
+}
+
+// ErrInvalidPolicy is returned from [NewMiddleware] when a [Policy]'s
+// configuration is invalid.
+var ErrInvalidPolicy = errors.New("invalid cache policy")
+
+type middleware struct {
+	policies []Policy
+}
+
+// Option defines a mutator type to configure a middleware.
+type Option func(*middleware)
+
+// WithPolicies is an [Option] that adds policies to the middleware's
+// configuration.
+func WithPolicies(policies ...Policy) Option {
+	return func(m *middleware) {
+		m.policies = append(m.policies, policies...)
+	}
+}
+
+// NewMiddleware creates a HTTP middleware that applies the caching policy
+// matching a request to the response. The policy is applied right before the
+// response header is written, so handlers may still decide otherwise:
+//
+//   - The policy's Cache-Control directives (see [response.Cache]) are only
+//     sent for GET and HEAD requests with a status code less than 400 and if
+//     the handler has not set a Cache-Control header itself.
+//   - The policy's Vary entries are added to the entries declared by handlers
+//     and other middlewares (i.e. Origin by cors or Accept by
+//     [response.Negotiate], see [httputils.AddVary]). All entries are merged
+//     into a single Vary header with duplicates removed.
+//
+// NewMiddleware returns an error if a policy has no or an invalid path or if
+// multiple policies use the same path.
+func NewMiddleware(opts ...Option) (httputils.Middleware, error) {
+	mw := &middleware{}
+
+	for _, opt := range opts {
+		opt(mw)
+	}
+
+	pm := &policyMux{
+		mux:      http.NewServeMux(),
+		policies: make(map[string]*Policy, len(mw.policies)),
+	}
+
+	for i := range mw.policies {
+		if err := pm.register(&mw.policies[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return func(handler http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rw := &responseWriter{
+				ResponseWriter: w,
+				r:              r,
+				policy:         pm.find(r),
+			}
+			handler.ServeHTTP(rw, r)
+
+			// Handlers returning without writing anything produce an implicit 200 response.
+			rw.applyPolicy(http.StatusOK)
+		})
+	}, nil
+}
+
+// policyMux finds the policy matching a request.
+type policyMux struct {
+	mux      *http.ServeMux
+	policies map[string]*Policy
+}
+
+// register registers p with m. It converts the panic raised by
+// [http.ServeMux.Handle] for invalid or conflicting patterns into an error.
+func (m *policyMux) register(p *Policy) (err error) {
+	if p.Path == "" {
+		return fmt.Errorf("%w: missing path", ErrInvalidPolicy)
+	}
+
+	if _, ok := m.policies[p.Path]; ok {
+		return fmt.Errorf("%w %q: duplicate path", ErrInvalidPolicy, p.Path)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w %q: %v", ErrInvalidPolicy, p.Path, r)
+		}
+	}()
+
+	m.mux.Handle(p.Path, http.NotFoundHandler())
+	m.policies[p.Path] = p
+	return nil
+}
+
+// find returns the policy applicable for r or nil if no policy matches.
+func (m *policyMux) find(r *http.Request) *Policy {
+	if len(m.policies) == 0 {
+		return nil
+	}
+
+	_, pattern := m.mux.Handler(r)
+	return m.policies[pattern]
+}
+
+// responseWriter wraps a [http.ResponseWriter] and applies a policy before the
+// header is written.
+type responseWriter struct {
+	http.ResponseWriter
+	r           *http.Request
+	policy      *Policy
+	wroteHeader bool
+}
+
+func (w *responseWriter) WriteHeader(statusCode int) {
+	w.applyPolicy(statusCode)
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *responseWriter) Write(buf []byte) (int, error) {
+	w.applyPolicy(http.StatusOK)
+	return w.ResponseWriter.Write(buf)
+}
+
+// Flush flushes the underlying [http.ResponseWriter] if it supports flushing.
+func (w *responseWriter) Flush() {
+	w.applyPolicy(http.StatusOK)
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap returns the underlying [http.ResponseWriter] to be used with
+// [http.ResponseController].
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// applyPolicy applies w's policy for a response with statusCode unless the
+// header has already been written. Informational responses are ignored.
+func (w *responseWriter) applyPolicy(statusCode int) {
+	if w.wroteHeader || statusCode < 200 {
+		return
+	}
+	w.wroteHeader = true
+
+	h := w.Header()
+
+	if w.policy != nil {
+		httputils.AddVary(h, w.policy.Vary...)
+
+		if !w.policy.CacheControl.IsZero() &&
+			h.Get("Cache-Control") == "" &&
+			statusCode < http.StatusBadRequest &&
+			(w.r.Method == http.MethodGet || w.r.Method == http.MethodHead) {
+			response.Cache(w.policy.CacheControl)(w.ResponseWriter, w.r)
+		}
+	}
+
+	mergeVary(h)
+}
+
+// mergeVary merges all Vary headers of h into a single one with duplicates
+// (compared case-insensitively) removed. If any entry is * only * is kept.
+func mergeVary(h http.Header) {
+	values := h.Values("Vary")
+	if len(values) == 0 {
+		return
+	}
+
+	var names []string
+	seen := make(map[string]struct{})
+
+	for _, v := range values {
+		for _, n := range strings.Split(v, ",") {
+			n = strings.TrimSpace(n)
+			if n == "" {
+				continue
+			}
+
+			if n == "*" {
+				h.Set("Vary", "*")
+				return
+			}
+
+			key := http.CanonicalHeaderKey(n)
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+			names = append(names, n)
+		}
+	}
+
+	if len(names) == 0 {
+		h.Del("Vary")
+		return
+	}
+
+	h.Set("Vary", strings.Join(names, ", "))
+}
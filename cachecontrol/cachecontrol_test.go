@@ -0,0 +1,139 @@
+package cachecontrol
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/halimath/expect"
+	"github.com/halimath/expect/is"
+	"github.com/halimath/httputils/requestbuilder"
+	"github.com/halimath/httputils/response"
+)
+
+func TestNewMiddleware(t *testing.T) {
+	static := response.CacheControl{}.Public().MaxAge(time.Hour).Immutable()
+	api := response.CacheControl{}.Private().NoCache()
+
+	mw, err := NewMiddleware(WithPolicies(
+		Policy{Path: "/static/", CacheControl: static},
+		Policy{Path: "/api/", CacheControl: api, Vary: []string{"Authorization"}},
+	))
+	expect.That(t, expect.FailNow(is.NoError(err)))
+
+	h := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/static/missing":
+			response.NotFound(w, r)
+		case "/api/custom":
+			response.PlainText(w, r, "custom", response.Cache(response.CacheControl{}.NoStore()))
+		case "/static/empty":
+			// Write nothing
+		case "/api/negotiate":
+			w.Header().Add("Vary", "Origin")
+			response.Negotiate(w, r, "hello")
+		default:
+			response.PlainText(w, r, "hello")
+		}
+	}))
+
+	serve := func(r *http.Request) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	t.Run("static", func(t *testing.T) {
+		w := serve(requestbuilder.Get("/static/app.js").Request())
+		expect.That(t,
+			is.EqualTo(w.Header().Get("Cache-Control"), "public, max-age=3600, immutable"),
+			is.EqualTo(w.Header().Get("Expires") != "", true),
+			is.EqualTo(w.Header().Get("Vary"), ""),
+		)
+	})
+
+	t.Run("nothing_written", func(t *testing.T) {
+		w := serve(requestbuilder.Get("/static/empty").Request())
+		expect.That(t,
+			is.EqualTo(w.Code, http.StatusOK),
+			is.EqualTo(w.Header().Get("Cache-Control"), "public, max-age=3600, immutable"),
+		)
+	})
+
+	t.Run("error_status", func(t *testing.T) {
+		w := serve(requestbuilder.Get("/static/missing").Request())
+		expect.That(t,
+			is.EqualTo(w.Code, http.StatusNotFound),
+			is.EqualTo(w.Header().Get("Cache-Control"), ""),
+		)
+	})
+
+	t.Run("unsafe_method", func(t *testing.T) {
+		w := serve(requestbuilder.Post("/static/app.js").Request())
+		expect.That(t, is.EqualTo(w.Header().Get("Cache-Control"), ""))
+	})
+
+	t.Run("handler_wins", func(t *testing.T) {
+		w := serve(requestbuilder.Get("/api/custom").Request())
+		expect.That(t,
+			is.EqualTo(w.Header().Get("Cache-Control"), "no-store"),
+			is.EqualTo(w.Header().Get("Vary"), "Authorization"),
+		)
+	})
+
+	t.Run("vary_merged", func(t *testing.T) {
+		w := serve(requestbuilder.Get("/api/negotiate").AddHeader("Accept", "application/json").Request())
+		expect.That(t,
+			is.EqualTo(w.Header().Get("Cache-Control"), "private, no-cache"),
+			is.DeepEqualTo(w.Header().Values("Vary"), []string{"Origin, Accept, Authorization"}),
+		)
+	})
+
+	t.Run("no_policy", func(t *testing.T) {
+		w := serve(requestbuilder.Get("/").Request())
+		expect.That(t,
+			is.EqualTo(w.Code, http.StatusOK),
+			is.EqualTo(w.Header().Get("Cache-Control"), ""),
+		)
+	})
+}
+
+func TestNewMiddleware_invalid(t *testing.T) {
+	for name, policies := range map[string][]Policy{
+		"missing_path": {{}},
+		"duplicate":    {{Path: "/a"}, {Path: "/a"}},
+		"invalid":      {{Path: "GET /{"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewMiddleware(WithPolicies(policies...))
+			expect.That(t, is.EqualTo(errors.Is(err, ErrInvalidPolicy), true))
+		})
+	}
+}
+
+func TestMergeVary(t *testing.T) {
+	tests := map[string]struct {
+		in   []string
+		want []string
+	}{
+		"none":     {nil, nil},
+		"single":   {[]string{"Accept"}, []string{"Accept"}},
+		"merged":   {[]string{"Accept, origin", "Origin", "accept-encoding"}, []string{"Accept, origin, accept-encoding"}},
+		"wildcard": {[]string{"Accept", "*"}, []string{"*"}},
+		"empty":    {[]string{" , "}, nil},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			h := http.Header{}
+			for _, v := range test.in {
+				h.Add("Vary", v)
+			}
+
+			mergeVary(h)
+			expect.That(t, is.DeepEqualTo(h.Values("Vary"), test.want))
+		})
+	}
+}
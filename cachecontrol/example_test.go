@@ -0,0 +1,33 @@
+package cachecontrol_test
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/halimath/httputils/cachecontrol"
+	"github.com/halimath/httputils/response"
+)
+
+func ExampleNewMiddleware() {
+	// mux is a http.Handler serving static assets and an API.
+	mux := http.NewServeMux()
+
+	cacheMiddleware, err := cachecontrol.NewMiddleware(
+		cachecontrol.WithPolicies(
+			cachecontrol.Policy{
+				Path:         "/assets/",
+				CacheControl: response.CacheControl{}.Public().MaxAge(365 * 24 * time.Hour).Immutable(),
+			},
+			cachecontrol.Policy{
+				Path:         "/api/",
+				CacheControl: response.CacheControl{}.Private().NoCache(),
+				Vary:         []string{"Authorization"},
+			},
+		),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	http.ListenAndServe(":8080", cacheMiddleware(mux))
+}
@@ -3,10 +3,18 @@
 package cors
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/halimath/glob"
 	"github.com/halimath/httputils"
+	"github.com/halimath/kvlog"
 )
 
 const (
@@ -28,9 +36,25 @@ const (
 	// ResponseHeaderAllowHeaders defines the response header to signal allowed headers.
 	ResponseHeaderAllowHeaders = "Access-Control-Allow-Headers"
 
+	// RequestHeaderPrivateNetwork defines the request header sent with preflight requests to a private network.
+	RequestHeaderPrivateNetwork = "Access-Control-Request-Private-Network"
+
 	// ResponseHeaderAllowCredential defines the response header to signal whether credentials are allowed.
 	ResponseHeaderAllowCredentials = "Access-Control-Allow-Credentials"
 
+	// ResponseHeaderExposeHeaders defines the response header to signal response headers exposed to scripts.
+	ResponseHeaderExposeHeaders = "Access-Control-Expose-Headers"
+
+	// ResponseHeaderMaxAge defines the response header to signal how long a preflight result may be cached.
+	ResponseHeaderMaxAge = "Access-Control-Max-Age"
+
+	// ResponseHeaderAllowPrivateNetwork defines the response header to signal that access to a private network
+	// is allowed.
+	ResponseHeaderAllowPrivateNetwork = "Access-Control-Allow-Private-Network"
+
+	// HeaderVary defines the Vary response header.
+	HeaderVary = "Vary"
+
 	// Wildcard defines the wildcard used as a value for several headers.
 	Wildcard = "*"
 )
@@ -39,17 +63,45 @@ const (
 // Endpoint a Path must be given as it identifies the endpoint. All other struct fields can be left empty
 // which marks the defaults as defined in the HTTP RFC. Set field values to customize resource sharing.
 type Endpoint struct {
-	// Path defines the endpoint's path and must be given.
+	// Path defines the endpoint's pattern and must be given. The pattern uses the same syntax as patterns
+	// registered with [http.ServeMux], i.e. /api/ matches all paths starting with /api/ while
+	// GET /orders/{id} only matches GET (and HEAD) requests to a single order. If multiple endpoints match a
+	// request, the most specific one is used as defined by [http.ServeMux].
+	//
+	// For compatibility with earlier versions, which matched paths as prefixes, a path that neither ends with
+	// a slash nor contains a wildcard also matches all paths below it, i.e. /api matches /api as well as
+	// /api/orders (but not /apis).
+	//
+	// For pre-flight requests, the method given in the Access-Control-Request-Method header is used for
+	// matching.
 	Path string
 
 	// AllowMethods defines the allowed HTTP methods. If left empty, no allow methods response header is
 	// sent which means that defaults apply.
 	AllowMethods []string
 
+	// AllowMethodsFrom is used to derive the allowed HTTP methods from the routes registered with a request
+	// multiplexer, such as [github.com/halimath/httputils/errmux.ServeMux]. It is only used when AllowMethods
+	// is empty. The allowed methods are determined for every pre-flight request based on the routes that
+	// match the request's path.
+	AllowMethodsFrom Router
+
 	// AllowOrigins defines the allowed origins to access the endpoint. If left empty or set to the wildcard,
-	// all origins are allowed.
+	// all origins are allowed (unless AllowOriginRegexps or AllowOriginFunc are given).
+	//
+	// Entries containing a wildcard are treated as patterns using [github.com/halimath/glob], i.e.
+	// https://*.preview.example.com matches all subdomains of preview.example.com and http://localhost:*
+	// matches localhost on any port. Origins are matched case-insensitive.
 	AllowOrigins []string
 
+	// AllowOriginRegexps lists regular expressions (using the [regexp] syntax) that are matched against the
+	// request's origin. Make sure to anchor the expressions with ^ and $.
+	AllowOriginRegexps []string
+
+	// AllowOriginFunc is an optional predicate to decide whether origin is allowed to access the endpoint
+	// with r. It is consulted if none of AllowOrigins and AllowOriginRegexps matches.
+	AllowOriginFunc func(origin string, r *http.Request) bool
+
 	// AllowHeaders lists the allowed headers for cross-origin requests. If left empty no allow headers
 	// response header is sent and the defaults apply.
 	AllowHeaders []string
@@ -57,39 +109,379 @@ type Endpoint struct {
 	// AllowCredentials specifies whether credentials are allowed and the respective response header is sent.
 	// If set to false (the default) the response header is not sent.
 	AllowCredentials bool
+
+	// ExposeHeaders lists the response headers that scripts are allowed to access. If left empty no expose
+	// headers response header is sent and only the CORS-safelisted response headers are exposed.
+	ExposeHeaders []string
+
+	// MaxAge defines how long the result of a preflight request may be cached by the client. If zero, no max
+	// age response header is sent and the client's default applies. A negative value disables caching.
+	MaxAge time.Duration
+
+	// AllowPrivateNetwork specifies whether preflight requests asking for private network access are
+	// answered positively.
+	AllowPrivateNetwork bool
+}
+
+// Router defines the interface for request multiplexers which are used to derive allowed methods for an
+// Endpoint. [github.com/halimath/httputils/errmux.ServeMux] satisfies this interface.
+type Router interface {
+	// AllowedMethods returns the methods for which the Router has a route matching r's host and path.
+	AllowedMethods(r *http.Request) []string
+}
+
+// compiledEndpoint is the internal representation of an Endpoint with all origin patterns being compiled.
+type compiledEndpoint struct {
+	Endpoint
+
+	allowAllOrigins bool
+	origins         map[string]struct{}
+	originPatterns  []originPattern
+	originRegexps   []*regexp.Regexp
+}
+
+// compile compiles e into a compiledEndpoint. It returns an error if any of the origin patterns or regular
+// expressions fail to compile.
+func compile(e Endpoint) (compiledEndpoint, error) {
+	c := compiledEndpoint{
+		Endpoint: e,
+		origins:  make(map[string]struct{}, len(e.AllowOrigins)),
+	}
+
+	for _, o := range e.AllowOrigins {
+		if o == Wildcard {
+			c.allowAllOrigins = true
+			continue
+		}
+
+		o = strings.ToLower(o)
+
+		if !strings.Contains(o, Wildcard) {
+			c.origins[o] = struct{}{}
+			continue
+		}
+
+		pat, err := compileOriginPattern(o)
+		if err != nil {
+			return c, err
+		}
+		c.originPatterns = append(c.originPatterns, pat)
+	}
+
+	for _, expr := range e.AllowOriginRegexps {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return c, fmt.Errorf("failed to compile origin regexp %q: %v", expr, err)
+		}
+		c.originRegexps = append(c.originRegexps, re)
+	}
+
+	if len(e.AllowOrigins) == 0 && len(e.AllowOriginRegexps) == 0 && e.AllowOriginFunc == nil {
+		c.allowAllOrigins = true
+	}
+
+	return c, nil
+}
+
+// originPattern implements a compiled origin pattern. As glob patterns treat slashes as separators, the
+// scheme is matched literally and only the remaining part (host and port) is matched using a glob pattern.
+type originPattern struct {
+	scheme string
+	host   *glob.Pattern
+}
+
+func compileOriginPattern(o string) (originPattern, error) {
+	scheme, host, ok := strings.Cut(o, "://")
+	if !ok {
+		return originPattern{}, fmt.Errorf("invalid origin pattern %q: missing scheme", o)
+	}
+
+	pat, err := glob.New(host)
+	if err != nil {
+		return originPattern{}, fmt.Errorf("failed to compile origin pattern %q: %v", o, err)
+	}
+
+	return originPattern{scheme: scheme, host: pat}, nil
 }
 
-// allowsOrigin tests whether the given origin is allowed by e. If AllowOrigins is empty of contains only the
-// wildcard, every origin is allowed. Otherwise the allowed origins are compared literally.
-func (e Endpoint) allowsOrigin(origin string) bool {
-	if len(e.AllowOrigins) == 0 {
+func (p originPattern) match(origin string) bool {
+	scheme, host, ok := strings.Cut(origin, "://")
+	return ok && scheme == p.scheme && p.host.Match(host)
+}
+
+// allowsOrigin tests whether the given origin is allowed by e. If no origin restrictions are configured or
+// AllowOrigins contains the wildcard, every origin is allowed. Otherwise the origin is compared literally,
+// matched against all patterns and regular expressions and finally passed to AllowOriginFunc.
+func (e compiledEndpoint) allowsOrigin(origin string, r *http.Request) bool {
+	if e.allowAllOrigins {
 		return true
 	}
 
-	if len(e.AllowOrigins) == 1 && e.AllowOrigins[0] == Wildcard {
+	lower := strings.ToLower(origin)
+
+	if _, ok := e.origins[lower]; ok {
 		return true
 	}
 
-	for _, o := range e.AllowOrigins {
-		if o == origin {
+	for _, pat := range e.originPatterns {
+		if pat.match(lower) {
+			return true
+		}
+	}
+
+	for _, re := range e.originRegexps {
+		if re.MatchString(origin) {
 			return true
 		}
 	}
 
+	if e.AllowOriginFunc != nil {
+		return e.AllowOriginFunc(origin, r)
+	}
+
 	return false
 }
 
 // allEndpoint is a sentinel value used in case no endpoints are given to the middleware. This endpoint is
 // then used to process requests.
-var allEndpoint = Endpoint{}
+var allEndpoint = &compiledEndpoint{Endpoint: Endpoint{}, allowAllOrigins: true}
+
+// endpointMux finds the endpoint matching a request. It uses a [http.ServeMux] to apply the same matching
+// and precedence rules as used for routing requests.
+type endpointMux struct {
+	mux       *http.ServeMux
+	endpoints map[string]*compiledEndpoint
+}
+
+// noopHandler is registered with the [http.ServeMux] for every endpoint. It is never invoked.
+var noopHandler = http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+
+// newEndpointMux creates an endpointMux for endpoints. Endpoints with duplicate paths are skipped; the first
+// endpoint declared for a path takes precedence. It returns an error if any of the paths is not a valid
+// pattern or conflicts with another one.
+func newEndpointMux(endpoints []compiledEndpoint) (m *endpointMux, err error) {
+	m = &endpointMux{
+		mux:       http.NewServeMux(),
+		endpoints: make(map[string]*compiledEndpoint, len(endpoints)),
+	}
+
+	for i := range endpoints {
+		if _, ok := m.endpoints[endpoints[i].Path]; ok {
+			continue
+		}
+
+		if err := m.register(endpoints[i].Path, &endpoints[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	// Register the subtree patterns for prefix paths after all explicit paths, so explicit paths take
+	// precedence.
+	for i := range endpoints {
+		subtree, ok := subtreePattern(endpoints[i].Path)
+		if !ok {
+			continue
+		}
+
+		if _, ok := m.endpoints[subtree]; ok {
+			continue
+		}
+
+		if err := m.register(subtree, &endpoints[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+// subtreePattern returns the pattern matching all paths below path for paths that neither end with a slash
+// nor contain a wildcard. Such paths have been matched as prefixes by earlier versions, so they cover their
+// subtree as well.
+func subtreePattern(path string) (string, bool) {
+	if strings.HasSuffix(path, "/") || strings.Contains(path, "{") {
+		return "", false
+	}
+	return path + "/", true
+}
+
+// register registers e with m for pattern. It converts the panic raised by [http.ServeMux.Handle] for
+// invalid or conflicting patterns into an error.
+func (m *endpointMux) register(pattern string, e *compiledEndpoint) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w %q: %v", ErrInvalidEndpoint, e.Path, r)
+		}
+	}()
+
+	m.mux.Handle(pattern, noopHandler)
+	m.endpoints[pattern] = e
+	return nil
+}
+
+// find determines the endpoint applicable for r. If no endpoints are configured, allEndpoint is returned.
+func (m *endpointMux) find(r *http.Request) (*compiledEndpoint, bool) {
+	if len(m.endpoints) == 0 {
+		return allEndpoint, true
+	}
+
+	if isPreflight(r) {
+		req := *r
+		req.Method = r.Header.Get(RequestHeaderMethod)
+		r = &req
+	}
+
+	_, pattern := m.mux.Handler(r)
+	e, ok := m.endpoints[pattern]
+	return e, ok
+}
+
+// DefaultDiagnosticHeader defines the default name of the response header used to report why a request has
+// been rejected when using [WithDiagnosticHeader].
+const DefaultDiagnosticHeader = "X-Cors-Diagnostic"
+
+var (
+	// ErrInvalidEndpoint is returned from [NewMiddleware] when an [Endpoint]'s configuration is invalid.
+	ErrInvalidEndpoint = errors.New("invalid cors endpoint")
+
+	// ErrNoEndpoint is reported when no [Endpoint] matches a request.
+	ErrNoEndpoint = errors.New("no endpoint matches request")
+
+	// ErrOriginNotAllowed is reported when a request's origin is not allowed.
+	ErrOriginNotAllowed = errors.New("origin not allowed")
+
+	// ErrMethodNotAllowed is reported when the method requested by a pre-flight request is not allowed.
+	ErrMethodNotAllowed = errors.New("method not allowed")
+
+	// ErrHeaderNotAllowed is reported when a header requested by a pre-flight request is not allowed.
+	ErrHeaderNotAllowed = errors.New("header not allowed")
+)
+
+type middleware struct {
+	endpoints        []Endpoint
+	debug            bool
+	diagnosticHeader string
+}
+
+// Option defines a mutator type to configure a middleware.
+type Option func(*middleware)
+
+// WithEndpoints is an [Option] that adds endpoints to the middleware's configuration. If no endpoints are
+// configured, all endpoints are accessible from all origins.
+func WithEndpoints(endpoints ...Endpoint) Option {
+	return func(m *middleware) {
+		m.endpoints = append(m.endpoints, endpoints...)
+	}
+}
+
+// WithDebug is an [Option] that enables debug logging. When enabled, the middleware logs every rejected
+// cross-origin request including the reason for rejection using the [kvlog.Logger] found in the request's
+// context.
+func WithDebug() Option {
+	return func(m *middleware) {
+		m.debug = true
+	}
+}
+
+// WithDiagnosticHeader is an [Option] that enables a diagnostic response header named name which contains
+// the reason why a cross-origin request has been rejected. If name is empty, [DefaultDiagnosticHeader] is
+// used. As the header discloses the CORS configuration, it should only be used during development.
+func WithDiagnosticHeader(name string) Option {
+	return func(m *middleware) {
+		if name == "" {
+			name = DefaultDiagnosticHeader
+		}
+		m.diagnosticHeader = name
+	}
+}
+
+// validate validates e and returns an error describing the first problem found.
+func validate(e Endpoint) error {
+	if e.Path == "" {
+		return fmt.Errorf("%w: missing path", ErrInvalidEndpoint)
+	}
+
+	if e.AllowCredentials && slices.Contains(e.AllowOrigins, Wildcard) {
+		return fmt.Errorf("%w %q: credentials must not be allowed for wildcard origins", ErrInvalidEndpoint, e.Path)
+	}
+
+	if e.AllowCredentials && !restrictsOrigins(e) {
+		return fmt.Errorf("%w %q: credentials must not be allowed without restricting origins", ErrInvalidEndpoint, e.Path)
+	}
+
+	if e.AllowCredentials && slices.Contains(e.ExposeHeaders, Wildcard) {
+		return fmt.Errorf("%w %q: credentials must not be allowed for wildcard expose headers", ErrInvalidEndpoint, e.Path)
+	}
+
+	return nil
+}
+
+// restrictsOrigins reports whether e defines any origin restriction.
+func restrictsOrigins(e Endpoint) bool {
+	return len(e.AllowOrigins) > 0 || len(e.AllowOriginRegexps) > 0 || e.AllowOriginFunc != nil
+}
+
+// NewMiddleware creates a HTTP middleware enabling Cross-Origin Resource Sharing by adding response headers
+// and handling pre-flight requests as defined by the [Fetch standard]. Use opts to configure the endpoints
+// and debugging.
+//
+// Pre-flight requests (using the HTTP method OPTIONS and carrying an Access-Control-Request-Method header)
+// are handled completely by this middleware and are not forwarded downstream. If the requested method or
+// headers are not allowed by the matching Endpoint, the pre-flight request is rejected with a status 403
+// and no CORS response headers are sent. Other requests (including OPTIONS requests which are no pre-flight
+// requests) are forwarded to handler but HTTP response headers are set beforehand.
+//
+// If an Endpoint allows all origins and does not allow credentials, the wildcard is sent as the allowed
+// origin. Otherwise, the request's origin is echoed and a Vary header is added.
+//
+// All endpoints are validated and all origin patterns and regular expressions are compiled when
+// NewMiddleware is invoked. NewMiddleware returns an error if any endpoint is invalid, i.e. if it allows
+// credentials for wildcard or unrestricted origins or uses an invalid path pattern. If multiple endpoints use the same path,
+// a warning is logged using [kvlog.L] and the first one is used.
+//
+// [Fetch standard]: https://fetch.spec.whatwg.org/#http-cors-protocol
+func NewMiddleware(opts ...Option) (httputils.Middleware, error) {
+	mw := &middleware{}
+
+	for _, opt := range opts {
+		opt(mw)
+	}
+
+	compiled := make([]compiledEndpoint, len(mw.endpoints))
+	paths := make(map[string]struct{}, len(mw.endpoints))
+
+	for i, e := range mw.endpoints {
+		if err := validate(e); err != nil {
+			return nil, err
+		}
+
+		if _, ok := paths[e.Path]; ok {
+			kvlog.L.Logs("cors: ignoring endpoint with duplicate path", kvlog.WithKV("path", e.Path))
+		}
+		paths[e.Path] = struct{}{}
+
+		c, err := compile(e)
+		if err != nil {
+			return nil, fmt.Errorf("%w %q: %v", ErrInvalidEndpoint, e.Path, err)
+		}
+		compiled[i] = c
+	}
+
+	endpointMux, err := newEndpointMux(compiled)
+	if err != nil {
+		return nil, err
+	}
 
-// Middleware creates a HTTP middleware enabling
-// Cross-Origin Resource Sharing by adding response headers and handling pre-flight requests.
-// Pre-flight requests (using the HTTP method OPTIONS) are handled completely by this middleware and are not
-// forwarded downstream. Other requests are forwarded to handler but HTTP response headers are set beforehand.
-func Middleware(endpoints ...Endpoint) httputils.Middleware {
 	return func(handler http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Determine the endpoint that's applicable for the request.
+			endpoint, ok := endpointMux.find(r)
+
+			if ok && endpoint.variesByOrigin() {
+				// Responses for this endpoint depend on the origin, so caches must be told so.
+				httputils.AddVary(w.Header(), RequestHeaderOrigin)
+			}
+
 			// Check if the request carries an Origin header.
 			if !isCrossOrigin(r) {
 				// If not, simply send it downstream.
@@ -97,56 +489,241 @@ func Middleware(endpoints ...Endpoint) httputils.Middleware {
 				return
 			}
 
-			// Determine the endpoint that's applicable for the request.
-			endpoint, ok := findEndpoint(r, endpoints)
-
-			if ok {
-				// If an endpoint has been configured, determine the origin.
-				origin := r.Header.Get(RequestHeaderOrigin)
-
-				if endpoint.allowsOrigin(origin) {
-					// If the origin is allowed by the endpoint configuration, add the respective Allow-* headers
-					// based on the configuration.
-					w.Header().Add(ResponseHeaderAllowOrigin, origin)
-
-					if len(endpoint.AllowMethods) > 0 {
-						w.Header().Add(ResponseHeaderAllowMethods, strings.Join(endpoint.AllowMethods, ", "))
-					}
-
-					if len(endpoint.AllowHeaders) > 0 {
-						w.Header().Add(ResponseHeaderAllowHeaders, strings.Join(endpoint.AllowHeaders, ", "))
-					}
-
-					if endpoint.AllowCredentials {
-						w.Header().Add(ResponseHeaderAllowCredentials, "true")
-					}
-				}
+			var err error
+			if !ok {
+				err = ErrNoEndpoint
 			}
 
 			if isPreflight(r) {
+				if err == nil {
+					err = endpoint.handlePreflight(w.Header(), r)
+				}
+
 				// If this is a preflight request, send a response and do not send the request downstream.
-				w.WriteHeader(http.StatusNoContent)
+				if err == nil {
+					w.WriteHeader(http.StatusNoContent)
+				} else {
+					mw.reject(w, r, err)
+					w.WriteHeader(http.StatusForbidden)
+				}
 				return
 			}
 
+			if err == nil {
+				err = endpoint.handleActual(w.Header(), r)
+			}
+
+			if err != nil {
+				mw.reject(w, r, err)
+			}
+
 			// In any other way, send the request downstream.
 			handler.ServeHTTP(w, r)
 		})
+	}, nil
+}
+
+// reject reports the rejection of r due to err using debug logging and the diagnostic header, if enabled.
+func (mw *middleware) reject(w http.ResponseWriter, r *http.Request, err error) {
+	if mw.debug {
+		kvlog.FromContext(r.Context()).Logs("cors: rejected cross-origin request",
+			kvlog.WithKV("method", r.Method),
+			kvlog.WithKV("path", r.URL.Path),
+			kvlog.WithKV("origin", r.Header.Get(RequestHeaderOrigin)),
+			kvlog.WithErr(err),
+		)
+	}
+
+	if mw.diagnosticHeader != "" {
+		w.Header().Set(mw.diagnosticHeader, err.Error())
 	}
 }
 
-func findEndpoint(r *http.Request, endpoints []Endpoint) (Endpoint, bool) {
-	if len(endpoints) == 0 {
-		return allEndpoint, true
+// Middleware creates a HTTP middleware enabling Cross-Origin Resource Sharing for endpoints. It works like
+// [NewMiddleware] with [WithEndpoints] but panics if the configuration is invalid. If no endpoints are given,
+// all endpoints are accessible from all origins.
+//
+// For compatibility with earlier versions, endpoints without a Path apply to all paths and endpoints which
+// allow credentials without restricting origins do not panic. For these endpoints, a warning is logged using
+// [kvlog.L] and credentials are not allowed, so origins are never echoed along with credentials.
+func Middleware(endpoints ...Endpoint) httputils.Middleware {
+	endpoints = slices.Clone(endpoints)
+	for i := range endpoints {
+		if endpoints[i].Path == "" {
+			endpoints[i].Path = "/"
+		}
+
+		if endpoints[i].AllowCredentials && !restrictsOrigins(endpoints[i]) {
+			kvlog.L.Logs("cors: ignoring credentials for endpoint without allowed origins", kvlog.WithKV("path", endpoints[i].Path))
+			endpoints[i].AllowCredentials = false
+		}
+	}
+
+	mw, err := NewMiddleware(WithEndpoints(endpoints...))
+	if err != nil {
+		panic(fmt.Sprintf("cors: %v", err))
+	}
+	return mw
+}
+
+// variesByOrigin reports whether the response headers sent for e depend on the request's origin.
+func (e compiledEndpoint) variesByOrigin() bool {
+	return !e.allowAllOrigins || e.AllowCredentials
+}
+
+// handleActual checks whether r is allowed according to e and adds the CORS response headers for an actual
+// (i.e. non-preflight) request to h. It returns an error describing why r has been rejected.
+func (e compiledEndpoint) handleActual(h http.Header, r *http.Request) error {
+	if err := e.addOriginHeaders(h, r); err != nil {
+		return err
 	}
 
-	for _, e := range endpoints {
-		if strings.HasPrefix(r.URL.Path, e.Path) {
-			return e, true
+	// Allowed methods and headers are only evaluated for pre-flight requests. They are sent for actual
+	// requests as well to stay compatible with earlier versions.
+	if len(e.AllowMethods) > 0 {
+		h.Set(ResponseHeaderAllowMethods, strings.Join(e.AllowMethods, ", "))
+	}
+
+	if len(e.AllowHeaders) > 0 && !(e.allowsAllHeaders() && e.AllowCredentials) {
+		h.Set(ResponseHeaderAllowHeaders, strings.Join(e.AllowHeaders, ", "))
+	}
+
+	if len(e.ExposeHeaders) > 0 {
+		h.Set(ResponseHeaderExposeHeaders, strings.Join(e.ExposeHeaders, ", "))
+	}
+
+	return nil
+}
+
+// handlePreflight checks whether the preflight request r is allowed according to e and adds the CORS response
+// headers to h. If r is not allowed, no headers are added and an error describing the reason is returned.
+func (e compiledEndpoint) handlePreflight(h http.Header, r *http.Request) error {
+	origin := r.Header.Get(RequestHeaderOrigin)
+	if !e.allowsOrigin(origin, r) {
+		return fmt.Errorf("%w: %s", ErrOriginNotAllowed, origin)
+	}
+
+	allowMethods := e.allowMethods(r)
+
+	method := r.Header.Get(RequestHeaderMethod)
+	if !e.allowsMethod(allowMethods, method) {
+		return fmt.Errorf("%w: %s", ErrMethodNotAllowed, method)
+	}
+
+	requestedHeaders := parseHeaderList(r.Header.Values(RequestHeaderHeaders))
+	for _, rh := range requestedHeaders {
+		if !e.allowsHeader(rh) {
+			return fmt.Errorf("%w: %s", ErrHeaderNotAllowed, rh)
+		}
+	}
+
+	e.addOriginHeaders(h, r)
+
+	if len(allowMethods) > 0 {
+		h.Set(ResponseHeaderAllowMethods, strings.Join(allowMethods, ", "))
+	}
+
+	if len(e.AllowHeaders) > 0 {
+		if e.allowsAllHeaders() && e.AllowCredentials {
+			// The wildcard is not supported for requests with credentials; echo the requested headers instead.
+			if len(requestedHeaders) > 0 {
+				h.Set(ResponseHeaderAllowHeaders, strings.Join(requestedHeaders, ", "))
+			}
+		} else {
+			h.Set(ResponseHeaderAllowHeaders, strings.Join(e.AllowHeaders, ", "))
 		}
 	}
 
-	return allEndpoint, false
+	if e.MaxAge > 0 {
+		h.Set(ResponseHeaderMaxAge, strconv.Itoa(int(e.MaxAge.Seconds())))
+	} else if e.MaxAge < 0 {
+		h.Set(ResponseHeaderMaxAge, "0")
+	}
+
+	if e.AllowPrivateNetwork && r.Header.Get(RequestHeaderPrivateNetwork) == "true" {
+		h.Set(ResponseHeaderAllowPrivateNetwork, "true")
+	}
+
+	return nil
+}
+
+// addOriginHeaders adds the allow origin and allow credentials headers to h if r's origin is allowed by e.
+func (e compiledEndpoint) addOriginHeaders(h http.Header, r *http.Request) error {
+	origin := r.Header.Get(RequestHeaderOrigin)
+	if !e.allowsOrigin(origin, r) {
+		return fmt.Errorf("%w: %s", ErrOriginNotAllowed, origin)
+	}
+
+	if e.allowAllOrigins && !e.AllowCredentials {
+		h.Set(ResponseHeaderAllowOrigin, Wildcard)
+	} else {
+		h.Set(ResponseHeaderAllowOrigin, origin)
+	}
+
+	if e.AllowCredentials {
+		h.Set(ResponseHeaderAllowCredentials, "true")
+	}
+
+	return nil
+}
+
+// safelistedMethods contains the CORS-safelisted methods which are always allowed.
+var safelistedMethods = []string{http.MethodGet, http.MethodHead, http.MethodPost}
+
+// allowMethods returns the methods allowed for the pre-flight request r. These are either the configured
+// AllowMethods or the methods derived from AllowMethodsFrom.
+func (e compiledEndpoint) allowMethods(r *http.Request) []string {
+	if len(e.AllowMethods) > 0 || e.AllowMethodsFrom == nil {
+		return e.AllowMethods
+	}
+
+	return e.AllowMethodsFrom.AllowedMethods(r)
+}
+
+// allowsMethod reports whether method is contained in allowMethods. If allowMethods is empty, only the
+// CORS-safelisted methods are allowed.
+func (e compiledEndpoint) allowsMethod(allowMethods []string, method string) bool {
+	if slices.Contains(safelistedMethods, method) {
+		return true
+	}
+
+	if slices.Contains(allowMethods, Wildcard) && !e.AllowCredentials {
+		return true
+	}
+
+	return slices.Contains(allowMethods, method)
+}
+
+// safelistedHeaders contains the (lower case) names of CORS-safelisted request headers which are always
+// allowed.
+var safelistedHeaders = []string{"accept", "accept-language", "content-language"}
+
+// allowsHeader reports whether the request header named header is allowed by e.
+func (e compiledEndpoint) allowsHeader(header string) bool {
+	if slices.Contains(safelistedHeaders, header) || e.allowsAllHeaders() {
+		return true
+	}
+
+	return slices.ContainsFunc(e.AllowHeaders, func(h string) bool { return strings.EqualFold(h, header) })
+}
+
+func (e compiledEndpoint) allowsAllHeaders() bool {
+	return slices.Contains(e.AllowHeaders, Wildcard)
+}
+
+// parseHeaderList parses the comma separated list of header names given in values and returns them in lower
+// case.
+func parseHeaderList(values []string) []string {
+	var headers []string
+	for _, v := range values {
+		for _, h := range strings.Split(v, ",") {
+			h = strings.ToLower(strings.TrimSpace(h))
+			if h != "" {
+				headers = append(headers, h)
+			}
+		}
+	}
+	return headers
 }
 
 func isCrossOrigin(r *http.Request) bool {
@@ -154,5 +731,5 @@ func isCrossOrigin(r *http.Request) bool {
 }
 
 func isPreflight(r *http.Request) bool {
-	return r.Method == http.MethodOptions
+	return r.Method == http.MethodOptions && r.Header.Get(RequestHeaderMethod) != ""
 }
@@ -5,9 +5,11 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/halimath/expect"
 	"github.com/halimath/expect/is"
+	"github.com/halimath/httputils/errmux"
 )
 
 var (
@@ -46,13 +48,15 @@ func TestMiddleware_corsRequest(t *testing.T) {
 
 	expect.That(t,
 		is.EqualTo(w.Result().StatusCode, http.StatusOK),
-		hasHTTPHeader(w.Header(), ResponseHeaderAllowOrigin, "https://example.com"),
+		hasHTTPHeader(w.Header(), ResponseHeaderAllowOrigin, Wildcard),
+		hasHTTPHeader(w.Header(), HeaderVary, ""),
 	)
 }
 
 func TestMiddleware_preflightRequest(t *testing.T) {
 	r := httptest.NewRequest(http.MethodOptions, "/", nil)
 	r.Header.Add(RequestHeaderOrigin, "https://example.com")
+	r.Header.Add(RequestHeaderMethod, http.MethodGet)
 	w := httptest.NewRecorder()
 
 	m := Middleware()(h)
@@ -60,7 +64,57 @@ func TestMiddleware_preflightRequest(t *testing.T) {
 
 	expect.That(t,
 		is.EqualTo(w.Result().StatusCode, http.StatusNoContent),
-		hasHTTPHeader(w.Header(), ResponseHeaderAllowOrigin, "https://example.com"),
+		hasHTTPHeader(w.Header(), ResponseHeaderAllowOrigin, Wildcard),
+	)
+}
+
+func TestMiddleware_optionsRequestWithoutRequestMethod(t *testing.T) {
+	r := httptest.NewRequest(http.MethodOptions, "/", nil)
+	r.Header.Add(RequestHeaderOrigin, "https://example.com")
+	w := httptest.NewRecorder()
+
+	m := Middleware()(h)
+	m.ServeHTTP(w, r)
+
+	expect.That(t,
+		is.EqualTo(w.Result().StatusCode, http.StatusOK),
+		is.EqualTo(w.Body.String(), "hello world"),
+		hasHTTPHeader(w.Header(), ResponseHeaderAllowOrigin, Wildcard),
+	)
+}
+
+func TestMiddleware_endpointWithoutPath(t *testing.T) {
+	r := httptest.NewRequest(http.MethodOptions, "/api/orders", nil)
+	r.Header.Add(RequestHeaderOrigin, "https://example.com")
+	r.Header.Add(RequestHeaderMethod, http.MethodPost)
+	w := httptest.NewRecorder()
+
+	m := Middleware(Endpoint{
+		AllowMethods: []string{http.MethodGet, http.MethodPost},
+	})(h)
+	m.ServeHTTP(w, r)
+
+	expect.That(t,
+		is.EqualTo(w.Result().StatusCode, http.StatusNoContent),
+		hasHTTPHeader(w.Header(), ResponseHeaderAllowMethods, "GET, POST"),
+	)
+}
+
+func TestMiddleware_credentialsWithoutOrigins(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Add(RequestHeaderOrigin, "https://example.com")
+	w := httptest.NewRecorder()
+
+	m := Middleware(Endpoint{
+		Path:             "/",
+		AllowCredentials: true,
+	})(h)
+	m.ServeHTTP(w, r)
+
+	expect.That(t,
+		is.EqualTo(w.Result().StatusCode, http.StatusOK),
+		hasHTTPHeader(w.Header(), ResponseHeaderAllowOrigin, Wildcard),
+		hasHTTPHeader(w.Header(), ResponseHeaderAllowCredentials, ""),
 	)
 }
 
@@ -71,6 +125,7 @@ func TestMiddleware_corsRequestWithCustomAllows(t *testing.T) {
 
 	m := Middleware(Endpoint{
 		Path:             "/",
+		AllowOrigins:     []string{"https://example.com"},
 		AllowMethods:     []string{http.MethodGet, http.MethodPost},
 		AllowHeaders:     []string{"Authorization"},
 		AllowCredentials: true,
@@ -86,6 +141,32 @@ func TestMiddleware_corsRequestWithCustomAllows(t *testing.T) {
 	)
 }
 
+func TestMiddleware_preflightRequestWithCustomAllows(t *testing.T) {
+	r := httptest.NewRequest(http.MethodOptions, "/", nil)
+	r.Header.Add(RequestHeaderOrigin, "https://example.com")
+	r.Header.Add(RequestHeaderMethod, http.MethodPost)
+	r.Header.Add(RequestHeaderHeaders, "authorization")
+	w := httptest.NewRecorder()
+
+	m := Middleware(Endpoint{
+		Path:             "/",
+		AllowOrigins:     []string{"https://example.com"},
+		AllowMethods:     []string{http.MethodGet, http.MethodPost},
+		AllowHeaders:     []string{"Authorization"},
+		AllowCredentials: true,
+	})(h)
+	m.ServeHTTP(w, r)
+
+	expect.That(t,
+		is.EqualTo(w.Result().StatusCode, http.StatusNoContent),
+		hasHTTPHeader(w.Header(), ResponseHeaderAllowOrigin, "https://example.com"),
+		hasHTTPHeader(w.Header(), HeaderVary, RequestHeaderOrigin),
+		hasHTTPHeader(w.Header(), ResponseHeaderAllowMethods, "GET, POST"),
+		hasHTTPHeader(w.Header(), ResponseHeaderAllowHeaders, "Authorization"),
+		hasHTTPHeader(w.Header(), ResponseHeaderAllowCredentials, "true"),
+	)
+}
+
 func TestMiddleware_corsRequestWithWildcardOrigin(t *testing.T) {
 	r := httptest.NewRequest(http.MethodGet, "/", nil)
 	r.Header.Add(RequestHeaderOrigin, "https://example.com")
@@ -99,7 +180,7 @@ func TestMiddleware_corsRequestWithWildcardOrigin(t *testing.T) {
 
 	expect.That(t,
 		is.EqualTo(w.Result().StatusCode, http.StatusOK),
-		hasHTTPHeader(w.Header(), ResponseHeaderAllowOrigin, "https://example.com"),
+		hasHTTPHeader(w.Header(), ResponseHeaderAllowOrigin, Wildcard),
 	)
 }
 
@@ -136,3 +217,367 @@ func TestMiddleware_corsRequestWithListedOriginsButNoneMatches(t *testing.T) {
 		hasHTTPHeader(w.Header(), ResponseHeaderAllowOrigin, ""),
 	)
 }
+
+func TestMiddleware_corsRequestWithOriginPatterns(t *testing.T) {
+	m := Middleware(Endpoint{
+		Path:               "/",
+		AllowOrigins:       []string{"https://*.preview.example.com", "http://localhost:*"},
+		AllowOriginRegexps: []string{`^https://review-\d+\.example\.com$`},
+		AllowOriginFunc: func(origin string, r *http.Request) bool {
+			return origin == "https://"+r.Host
+		},
+	})(h)
+
+	tab := map[string]string{
+		"https://pr-17.preview.example.com": "https://pr-17.preview.example.com",
+		"https://PR-17.Preview.Example.com": "https://PR-17.Preview.Example.com",
+		"https://preview.example.com":       "",
+		"http://localhost:8080":             "http://localhost:8080",
+		"https://localhost:8080":            "",
+		"https://review-42.example.com":     "https://review-42.example.com",
+		"https://review-x.example.com":      "",
+		"https://example.com":               "https://example.com",
+		"https://foobar.com":                "",
+	}
+
+	for origin, want := range tab {
+		t.Run(origin, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Add(RequestHeaderOrigin, origin)
+			w := httptest.NewRecorder()
+
+			m.ServeHTTP(w, r)
+
+			expect.That(t, hasHTTPHeader(w.Header(), ResponseHeaderAllowOrigin, want))
+		})
+	}
+}
+
+func TestMiddleware_invalidOriginRegexp(t *testing.T) {
+	defer func() {
+		expect.That(t, is.EqualTo(recover() != nil, true))
+	}()
+
+	Middleware(Endpoint{
+		Path:               "/",
+		AllowOriginRegexps: []string{"("},
+	})
+}
+
+func TestMiddleware_preflight(t *testing.T) {
+	m := Middleware(Endpoint{
+		Path:                "/",
+		AllowOrigins:        []string{"https://example.com"},
+		AllowMethods:        []string{http.MethodPut, http.MethodDelete},
+		AllowHeaders:        []string{"Authorization", "Content-Type"},
+		ExposeHeaders:       []string{"X-Request-Id"},
+		MaxAge:              10 * time.Minute,
+		AllowPrivateNetwork: true,
+	})(h)
+
+	t.Run("allowed", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodOptions, "/", nil)
+		r.Header.Add(RequestHeaderOrigin, "https://example.com")
+		r.Header.Add(RequestHeaderMethod, http.MethodPut)
+		r.Header.Add(RequestHeaderHeaders, "content-type, authorization")
+		r.Header.Add(RequestHeaderPrivateNetwork, "true")
+		w := httptest.NewRecorder()
+
+		m.ServeHTTP(w, r)
+
+		expect.That(t,
+			is.EqualTo(w.Result().StatusCode, http.StatusNoContent),
+			hasHTTPHeader(w.Header(), ResponseHeaderAllowOrigin, "https://example.com"),
+			hasHTTPHeader(w.Header(), ResponseHeaderAllowMethods, "PUT, DELETE"),
+			hasHTTPHeader(w.Header(), ResponseHeaderAllowHeaders, "Authorization, Content-Type"),
+			hasHTTPHeader(w.Header(), ResponseHeaderMaxAge, "600"),
+			hasHTTPHeader(w.Header(), ResponseHeaderAllowPrivateNetwork, "true"),
+			hasHTTPHeader(w.Header(), ResponseHeaderExposeHeaders, ""),
+			hasHTTPHeader(w.Header(), HeaderVary, RequestHeaderOrigin),
+		)
+	})
+
+	t.Run("methodNotAllowed", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodOptions, "/", nil)
+		r.Header.Add(RequestHeaderOrigin, "https://example.com")
+		r.Header.Add(RequestHeaderMethod, http.MethodPatch)
+		w := httptest.NewRecorder()
+
+		m.ServeHTTP(w, r)
+
+		expect.That(t,
+			is.EqualTo(w.Result().StatusCode, http.StatusForbidden),
+			hasHTTPHeader(w.Header(), ResponseHeaderAllowOrigin, ""),
+		)
+	})
+
+	t.Run("headerNotAllowed", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodOptions, "/", nil)
+		r.Header.Add(RequestHeaderOrigin, "https://example.com")
+		r.Header.Add(RequestHeaderMethod, http.MethodPut)
+		r.Header.Add(RequestHeaderHeaders, "x-custom")
+		w := httptest.NewRecorder()
+
+		m.ServeHTTP(w, r)
+
+		expect.That(t,
+			is.EqualTo(w.Result().StatusCode, http.StatusForbidden),
+			hasHTTPHeader(w.Header(), ResponseHeaderAllowOrigin, ""),
+		)
+	})
+
+	t.Run("actual", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPut, "/", nil)
+		r.Header.Add(RequestHeaderOrigin, "https://example.com")
+		w := httptest.NewRecorder()
+
+		m.ServeHTTP(w, r)
+
+		expect.That(t,
+			is.EqualTo(w.Result().StatusCode, http.StatusOK),
+			hasHTTPHeader(w.Header(), ResponseHeaderAllowOrigin, "https://example.com"),
+			hasHTTPHeader(w.Header(), ResponseHeaderExposeHeaders, "X-Request-Id"),
+			hasHTTPHeader(w.Header(), ResponseHeaderAllowMethods, "PUT, DELETE"),
+			hasHTTPHeader(w.Header(), ResponseHeaderMaxAge, ""),
+		)
+	})
+
+	t.Run("sameOrigin", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		w := httptest.NewRecorder()
+
+		m.ServeHTTP(w, r)
+
+		expect.That(t,
+			is.EqualTo(w.Result().StatusCode, http.StatusOK),
+			hasHTTPHeader(w.Header(), HeaderVary, RequestHeaderOrigin),
+		)
+	})
+}
+
+func TestMiddleware_preflightWithWildcardHeadersAndCredentials(t *testing.T) {
+	m := Middleware(Endpoint{
+		Path:             "/",
+		AllowOrigins:     []string{"https://example.com"},
+		AllowHeaders:     []string{Wildcard},
+		AllowCredentials: true,
+	})(h)
+
+	r := httptest.NewRequest(http.MethodOptions, "/", nil)
+	r.Header.Add(RequestHeaderOrigin, "https://example.com")
+	r.Header.Add(RequestHeaderMethod, http.MethodGet)
+	r.Header.Add(RequestHeaderHeaders, "x-foo,x-bar")
+	w := httptest.NewRecorder()
+
+	m.ServeHTTP(w, r)
+
+	expect.That(t,
+		is.EqualTo(w.Result().StatusCode, http.StatusNoContent),
+		hasHTTPHeader(w.Header(), ResponseHeaderAllowOrigin, "https://example.com"),
+		hasHTTPHeader(w.Header(), ResponseHeaderAllowCredentials, "true"),
+		hasHTTPHeader(w.Header(), ResponseHeaderAllowHeaders, "x-foo, x-bar"),
+	)
+}
+
+func TestMiddleware_prefixPath(t *testing.T) {
+	m := Middleware(
+		Endpoint{
+			Path:         "/api",
+			AllowOrigins: []string{"https://example.com"},
+		},
+		Endpoint{
+			Path:         "/api/admin/",
+			AllowOrigins: []string{"https://admin.example.com"},
+		},
+	)(h)
+
+	tab := map[string]string{
+		"/api":            "https://example.com",
+		"/api/orders/17":  "https://example.com",
+		"/api/admin/user": "",
+		"/apis":           "",
+	}
+
+	for path, want := range tab {
+		t.Run(path, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, path, nil)
+			r.Header.Add(RequestHeaderOrigin, "https://example.com")
+			w := httptest.NewRecorder()
+
+			m.ServeHTTP(w, r)
+
+			expect.That(t, hasHTTPHeader(w.Header(), ResponseHeaderAllowOrigin, want))
+		})
+	}
+}
+
+func TestMiddleware_endpointPatterns(t *testing.T) {
+	m := Middleware(
+		Endpoint{
+			Path:         "/api/",
+			AllowOrigins: []string{"https://example.com"},
+		},
+		Endpoint{
+			Path:         "/api/admin/",
+			AllowOrigins: []string{"https://admin.example.com"},
+		},
+		Endpoint{
+			Path:         "DELETE /orders/{id}",
+			AllowOrigins: []string{"https://orders.example.com"},
+			AllowMethods: []string{http.MethodDelete},
+		},
+	)(h)
+
+	tab := []struct {
+		method, path, origin, want string
+	}{
+		{http.MethodGet, "/api/foo", "https://example.com", "https://example.com"},
+		{http.MethodGet, "/api/admin/foo", "https://example.com", ""},
+		{http.MethodGet, "/api/admin/foo", "https://admin.example.com", "https://admin.example.com"},
+		{http.MethodGet, "/apiary", "https://example.com", ""},
+		{http.MethodDelete, "/orders/17", "https://orders.example.com", "https://orders.example.com"},
+		{http.MethodGet, "/orders/17", "https://orders.example.com", ""},
+	}
+
+	for _, test := range tab {
+		t.Run(test.method+" "+test.path+" "+test.origin, func(t *testing.T) {
+			r := httptest.NewRequest(test.method, test.path, nil)
+			r.Header.Add(RequestHeaderOrigin, test.origin)
+			w := httptest.NewRecorder()
+
+			m.ServeHTTP(w, r)
+
+			expect.That(t, hasHTTPHeader(w.Header(), ResponseHeaderAllowOrigin, test.want))
+		})
+	}
+
+	t.Run("preflightUsesRequestedMethod", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodOptions, "/orders/17", nil)
+		r.Header.Add(RequestHeaderOrigin, "https://orders.example.com")
+		r.Header.Add(RequestHeaderMethod, http.MethodDelete)
+		w := httptest.NewRecorder()
+
+		m.ServeHTTP(w, r)
+
+		expect.That(t,
+			is.EqualTo(w.Result().StatusCode, http.StatusNoContent),
+			hasHTTPHeader(w.Header(), ResponseHeaderAllowOrigin, "https://orders.example.com"),
+			hasHTTPHeader(w.Header(), ResponseHeaderAllowMethods, http.MethodDelete),
+		)
+	})
+}
+
+func TestMiddleware_allowMethodsFrom(t *testing.T) {
+	mux := errmux.NewServeMux()
+	noop := func(http.ResponseWriter, *http.Request) error { return nil }
+	mux.HandleFunc("GET /orders/{id}", noop)
+	mux.HandleFunc("PUT /orders/{id}", noop)
+	mux.HandleFunc("POST /orders/", noop)
+
+	m := Middleware(Endpoint{
+		Path:             "/orders/",
+		AllowMethodsFrom: mux,
+	})(mux)
+
+	t.Run("allowed", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodOptions, "/orders/17", nil)
+		r.Header.Add(RequestHeaderOrigin, "https://example.com")
+		r.Header.Add(RequestHeaderMethod, http.MethodPut)
+		w := httptest.NewRecorder()
+
+		m.ServeHTTP(w, r)
+
+		expect.That(t,
+			is.EqualTo(w.Result().StatusCode, http.StatusNoContent),
+			hasHTTPHeader(w.Header(), ResponseHeaderAllowMethods, "GET, HEAD, POST, PUT"),
+		)
+	})
+
+	t.Run("notAllowed", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodOptions, "/orders/17", nil)
+		r.Header.Add(RequestHeaderOrigin, "https://example.com")
+		r.Header.Add(RequestHeaderMethod, http.MethodDelete)
+		w := httptest.NewRecorder()
+
+		m.ServeHTTP(w, r)
+
+		expect.That(t, is.EqualTo(w.Result().StatusCode, http.StatusForbidden))
+	})
+}
+
+func TestNewMiddleware_validation(t *testing.T) {
+	tab := map[string][]Endpoint{
+		"missingPath":               {{AllowMethods: []string{http.MethodGet}}},
+		"credentialsWithWildcard":   {{Path: "/", AllowOrigins: []string{Wildcard}, AllowCredentials: true}},
+		"credentialsWithoutOrigins": {{Path: "/", AllowCredentials: true}},
+		"credentialsWithExpose":     {{Path: "/", AllowOrigins: []string{"https://example.com"}, ExposeHeaders: []string{Wildcard}, AllowCredentials: true}},
+		"invalidPattern":            {{Path: "GET"}},
+		"conflictingPatterns":       {{Path: "/a/{x}"}, {Path: "/{y}/b"}},
+		"invalidOriginPattern":      {{Path: "/", AllowOrigins: []string{"*.example.com"}}},
+	}
+
+	for name, endpoints := range tab {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewMiddleware(WithEndpoints(endpoints...))
+			expect.That(t, is.Error(err, ErrInvalidEndpoint))
+		})
+	}
+
+	t.Run("duplicatePath", func(t *testing.T) {
+		m, err := NewMiddleware(WithEndpoints(
+			Endpoint{Path: "/", AllowOrigins: []string{"https://example.com"}},
+			Endpoint{Path: "/", AllowOrigins: []string{"https://foobar.com"}},
+		))
+		expect.That(t, is.NoError(err))
+
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Add(RequestHeaderOrigin, "https://foobar.com")
+		w := httptest.NewRecorder()
+
+		m(h).ServeHTTP(w, r)
+
+		expect.That(t, hasHTTPHeader(w.Header(), ResponseHeaderAllowOrigin, ""))
+	})
+}
+
+func TestNewMiddleware_diagnosticHeader(t *testing.T) {
+	m, err := NewMiddleware(
+		WithEndpoints(Endpoint{
+			Path:         "/api/",
+			AllowOrigins: []string{"https://example.com"},
+			AllowHeaders: []string{"Authorization"},
+		}),
+		WithDebug(),
+		WithDiagnosticHeader(""),
+	)
+	expect.That(t, is.NoError(err))
+
+	tab := map[string]struct {
+		method, path, origin, requestMethod, requestHeaders string
+		want                                                string
+	}{
+		"origin":     {http.MethodGet, "/api/foo", "https://foobar.com", "", "", "origin not allowed: https://foobar.com"},
+		"noEndpoint": {http.MethodGet, "/foo", "https://example.com", "", "", "no endpoint matches request"},
+		"method":     {http.MethodOptions, "/api/foo", "https://example.com", http.MethodPut, "", "method not allowed: PUT"},
+		"header":     {http.MethodOptions, "/api/foo", "https://example.com", http.MethodGet, "x-foo", "header not allowed: x-foo"},
+		"allowed":    {http.MethodOptions, "/api/foo", "https://example.com", http.MethodGet, "authorization", ""},
+	}
+
+	for name, test := range tab {
+		t.Run(name, func(t *testing.T) {
+			r := httptest.NewRequest(test.method, test.path, nil)
+			r.Header.Add(RequestHeaderOrigin, test.origin)
+			if test.requestMethod != "" {
+				r.Header.Add(RequestHeaderMethod, test.requestMethod)
+			}
+			if test.requestHeaders != "" {
+				r.Header.Add(RequestHeaderHeaders, test.requestHeaders)
+			}
+			w := httptest.NewRecorder()
+
+			m(h).ServeHTTP(w, r)
+
+			expect.That(t, hasHTTPHeader(w.Header(), DefaultDiagnosticHeader, test.want))
+		})
+	}
+}
@@ -24,9 +24,32 @@ func Example_customOrigins() {
 			},
 			cors.Endpoint{
 				Path:             "/api/v1/resource2",
+				AllowOrigins:     []string{"https://app.example.com"},
 				AllowMethods:     []string{http.MethodPost},
 				AllowCredentials: true,
 			},
 		)(restAPI),
 	)
 }
+
+func ExampleNewMiddleware() {
+	// restAPI is a http.Handler that defines some kind of resource.
+	restAPI := http.NewServeMux()
+
+	corsMiddleware, err := cors.NewMiddleware(
+		cors.WithEndpoints(
+			cors.Endpoint{
+				Path:             "/api/",
+				AllowOrigins:     []string{"https://*.example.com"},
+				AllowCredentials: true,
+			},
+		),
+		cors.WithDebug(),
+		cors.WithDiagnosticHeader(cors.DefaultDiagnosticHeader),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	http.ListenAndServe(":1234", corsMiddleware(restAPI))
+}
@@ -0,0 +1,403 @@
+// Package csrf provides a HTTP middleware protecting applications against
+// cross-site request forgery (CSRF).
+//
+// The middleware supports three different modes of operation:
+//
+//   - [ModeSynchronizerToken] stores a random token in the [session.Session]
+//     associated with the request. Clients must send this token with every
+//     unsafe request either as a request header or as a form field. This mode
+//     requires the [session.NewMiddleware] to run before the csrf middleware.
+//   - [ModeDoubleSubmitCookie] works stateless by storing the token in a cookie
+//     and requiring the client to echo the cookie's value in a request header
+//     or form field.
+//   - [ModeOriginCheck] needs no token at all but verifies the Sec-Fetch-Site
+//     and Origin request headers sent by browsers.
+//
+// Requests using a safe method (GET, HEAD, OPTIONS and TRACE) are never
+// rejected. Unsafe requests that fail the check are rejected with a status 403
+// sent via [response.Forbidden].
+package csrf
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/halimath/httputils"
+	"github.com/halimath/httputils/internal/secure"
+	"github.com/halimath/httputils/response"
+	"github.com/halimath/httputils/session"
+	"github.com/halimath/kvlog"
+)
+
+const (
+	// DefaultHeaderName defines the default name of the request header to
+	// carry the CSRF token.
+	DefaultHeaderName = "X-CSRF-Token"
+
+	// DefaultFieldName defines the default name of the form field to carry
+	// the CSRF token.
+	DefaultFieldName = "csrf_token"
+
+	// DefaultCookieName defines the default name of the cookie used with
+	// [ModeDoubleSubmitCookie].
+	DefaultCookieName = "csrf_token"
+
+	// SessionKey defines the key used to store the token in the
+	// [session.Session] when using [ModeSynchronizerToken].
+	SessionKey = "csrf_token"
+
+	// HeaderSecFetchSite contains the name of the Sec-Fetch-Site request
+	// header.
+	HeaderSecFetchSite = "Sec-Fetch-Site"
+
+	// HeaderOrigin contains the name of the Origin request header.
+	HeaderOrigin = "Origin"
+)
+
+// Mode defines the mode of operation of the middleware.
+type Mode int
+
+const (
+	// ModeSynchronizerToken stores the token in the request's session.
+	ModeSynchronizerToken Mode = iota
+
+	// ModeDoubleSubmitCookie stores the token in a cookie.
+	ModeDoubleSubmitCookie
+
+	// ModeOriginCheck verifies the Sec-Fetch-Site and Origin request headers.
+	ModeOriginCheck
+)
+
+var (
+	// ErrNoSession is reported when the middleware is used in
+	// [ModeSynchronizerToken] but no session is associated with a request.
+	ErrNoSession = errors.New("csrf: no session found in request context")
+)
+
+// CookieOpts defines the options for the cookie used with
+// [ModeDoubleSubmitCookie].
+type CookieOpts struct {
+	Name     string
+	Path     string
+	Domain   string
+	SameSite http.SameSite
+}
+
+type middleware struct {
+	mode           Mode
+	headerName     string
+	fieldName      string
+	cookie         CookieOpts
+	trustedOrigins map[string]struct{}
+}
+
+// Option defines a mutator type to configure a middleware.
+type Option func(*middleware)
+
+// WithMode is an [Option] that configures the mode of operation.
+func WithMode(mode Mode) Option {
+	return func(m *middleware) {
+		m.mode = mode
+	}
+}
+
+// WithHeaderName is an [Option] that configures the name of the request header
+// to read the token from.
+func WithHeaderName(name string) Option {
+	return func(m *middleware) {
+		m.headerName = name
+	}
+}
+
+// WithFieldName is an [Option] that configures the name of the form field to
+// read the token from.
+func WithFieldName(name string) Option {
+	return func(m *middleware) {
+		m.fieldName = name
+	}
+}
+
+// WithCookieOptions is an [Option] that customizes the cookie used with
+// [ModeDoubleSubmitCookie].
+func WithCookieOptions(opts CookieOpts) Option {
+	return func(m *middleware) {
+		if opts.Name != "" {
+			m.cookie.Name = opts.Name
+		}
+		if opts.Path != "" {
+			m.cookie.Path = opts.Path
+		}
+		if opts.Domain != "" {
+			m.cookie.Domain = opts.Domain
+		}
+		if opts.SameSite != 0 {
+			m.cookie.SameSite = opts.SameSite
+		}
+	}
+}
+
+// WithTrustedOrigins is an [Option] that adds origins (i.e.
+// https://example.com) which are allowed to send unsafe requests in addition
+// to the request's own origin. This is only used with [ModeOriginCheck].
+func WithTrustedOrigins(origins ...string) Option {
+	return func(m *middleware) {
+		for _, o := range origins {
+			m.trustedOrigins[strings.ToLower(o)] = struct{}{}
+		}
+	}
+}
+
+// NewMiddleware creates a new HTTP middleware that adds CSRF protection. By
+// default, the middleware uses [ModeSynchronizerToken], reads the token from
+// the [DefaultHeaderName] request header or the [DefaultFieldName] form field.
+// Use opts to customize the behavior.
+//
+// For both token based modes the token is stored in the request's context;
+// use [Token] or [TemplateField] to retrieve it when rendering responses.
+func NewMiddleware(opts ...Option) httputils.Middleware {
+	mw := &middleware{
+		mode:       ModeSynchronizerToken,
+		headerName: DefaultHeaderName,
+		fieldName:  DefaultFieldName,
+		cookie: CookieOpts{
+			Name:     DefaultCookieName,
+			Path:     "/",
+			SameSite: http.SameSiteStrictMode,
+		},
+		trustedOrigins: make(map[string]struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(mw)
+	}
+
+	return func(handler http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			logger := kvlog.FromContext(r.Context())
+
+			var token string
+
+			switch mw.mode {
+			case ModeSynchronizerToken:
+				var err error
+				token, err = mw.sessionToken(r)
+				if err != nil {
+					logger.Logs("failed to determine csrf token", kvlog.WithErr(err))
+					response.Error(w, r, err)
+					return
+				}
+			case ModeDoubleSubmitCookie:
+				token = mw.cookieToken(w, r)
+			}
+
+			if !isSafeMethod(r.Method) {
+				var err error
+				if mw.mode == ModeOriginCheck {
+					err = mw.checkOrigin(r)
+				} else {
+					err = mw.checkToken(r, token)
+				}
+
+				if err != nil {
+					logger.Logs("rejecting request due to failed csrf check", kvlog.WithKV("method", r.Method), kvlog.WithKV("path", r.URL.Path), kvlog.WithErr(err))
+					response.Forbidden(w, r)
+					return
+				}
+			}
+
+			if token != "" {
+				r = r.WithContext(withToken(r.Context(), tokenInfo{token: token, fieldName: mw.fieldName}))
+			}
+
+			handler.ServeHTTP(w, r)
+		})
+	}
+}
+
+// sessionToken returns the token stored in r's session. If no token has been
+// stored so far, a new one is generated and stored.
+func (mw *middleware) sessionToken(r *http.Request) (string, error) {
+	ses := session.FromRequest(r)
+	if ses == nil {
+		return "", ErrNoSession
+	}
+
+	token := session.Get[string](ses, SessionKey)
+	if token == "" {
+		token = GenerateToken()
+		ses.Set(SessionKey, token)
+	}
+
+	return token, nil
+}
+
+// cookieToken returns the token stored in r's cookie. If no such cookie
+// exists, a new token is generated and set as a cookie on w. The cookie is not
+// HttpOnly to allow scripts to read the token and send it as a request header.
+func (mw *middleware) cookieToken(w http.ResponseWriter, r *http.Request) string {
+	if c, err := r.Cookie(mw.cookie.Name); err == nil && c.Value != "" {
+		return c.Value
+	}
+
+	token := GenerateToken()
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     mw.cookie.Name,
+		Value:    token,
+		Domain:   mw.cookie.Domain,
+		Path:     mw.cookie.Path,
+		Secure:   secure.IsSecureRequest(r),
+		SameSite: mw.cookie.SameSite,
+	})
+
+	return token
+}
+
+var (
+	errTokenMissing  = errors.New("csrf token missing")
+	errTokenMismatch = errors.New("csrf token mismatch")
+	errCrossSite     = errors.New("cross-site request")
+	errOrigin        = errors.New("untrusted origin")
+)
+
+// checkToken compares the token sent with r to want using a constant time
+// comparison.
+func (mw *middleware) checkToken(r *http.Request, want string) error {
+	got := r.Header.Get(mw.headerName)
+	if got == "" {
+		got = r.PostFormValue(mw.fieldName)
+	}
+
+	if got == "" {
+		return errTokenMissing
+	}
+
+	if subtle.ConstantTimeCompare([]byte(got), []byte(want)) != 1 {
+		return errTokenMismatch
+	}
+
+	return nil
+}
+
+// checkOrigin verifies r's Sec-Fetch-Site and Origin headers. Requests that
+// carry neither of them are not issued by a (modern) browser and are allowed.
+func (mw *middleware) checkOrigin(r *http.Request) error {
+	switch r.Header.Get(HeaderSecFetchSite) {
+	case "":
+		// Not set; fall back to the Origin header
+	case "same-origin", "none":
+		return nil
+	default:
+		if mw.isTrustedOrigin(r.Header.Get(HeaderOrigin)) {
+			return nil
+		}
+		return errCrossSite
+	}
+
+	origin := r.Header.Get(HeaderOrigin)
+	if origin == "" {
+		return nil
+	}
+
+	u, err := url.Parse(origin)
+	if err == nil && strings.EqualFold(u.Host, r.Host) {
+		return nil
+	}
+
+	if mw.isTrustedOrigin(origin) {
+		return nil
+	}
+
+	return fmt.Errorf("%w: %s", errOrigin, origin)
+}
+
+func (mw *middleware) isTrustedOrigin(origin string) bool {
+	if origin == "" {
+		return false
+	}
+	_, ok := mw.trustedOrigins[strings.ToLower(origin)]
+	return ok
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	default:
+		return false
+	}
+}
+
+// --
+
+// Private type for the context key
+type contextKeyType string
+
+// Sentinel value used as the context key to hold the token.
+const contextKey contextKeyType = "csrfToken"
+
+// tokenInfo is stored in a request's context and holds the token as well as
+// the form field name configured for the middleware.
+type tokenInfo struct {
+	token     string
+	fieldName string
+}
+
+func withToken(ctx context.Context, ti tokenInfo) context.Context {
+	return context.WithValue(ctx, contextKey, ti)
+}
+
+// FromContext returns the CSRF token associated with ctx. If no token exists,
+// the empty string is returned.
+func FromContext(ctx context.Context) string {
+	ti, _ := ctx.Value(contextKey).(tokenInfo)
+	return ti.token
+}
+
+// Token returns the CSRF token associated with r. This is equivalent to
+//
+//	FromContext(r.Context())
+//
+// Single page applications may send the token as the [DefaultHeaderName]
+// request header.
+func Token(r *http.Request) string {
+	return FromContext(r.Context())
+}
+
+// TemplateField returns a hidden HTML input element containing the token
+// associated with r using the configured form field name. The result can be
+// used directly within an [html/template]. If r carries no token, an empty
+// string is returned.
+func TemplateField(r *http.Request) template.HTML {
+	ti, _ := r.Context().Value(contextKey).(tokenInfo)
+	if ti.token == "" {
+		return ""
+	}
+
+	return template.HTML(fmt.Sprintf(`<input type="hidden" name="%s" value="%s">`,
+		template.HTMLEscapeString(ti.fieldName), template.HTMLEscapeString(ti.token)))
+}
+
+// --
+
+const tokenBytes = 32 // 32 bytes = 256 bits of entropy
+
+// GenerateToken generates a random, cryptographically secure token.
+func GenerateToken() string {
+	buf := make([]byte, tokenBytes)
+
+	_, err := rand.Read(buf)
+	if err != nil {
+		panic(fmt.Sprintf("unable to generate csrf token: %v", err))
+	}
+
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
@@ -0,0 +1,154 @@
+package csrf
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/halimath/expect"
+	"github.com/halimath/expect/is"
+	"github.com/halimath/httputils"
+	"github.com/halimath/httputils/requestbuilder"
+	"github.com/halimath/httputils/session"
+)
+
+var h = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set(DefaultHeaderName, Token(r))
+	w.WriteHeader(http.StatusOK)
+})
+
+func TestMiddleware_synchronizerToken(t *testing.T) {
+	store := session.NewInMemoryStore()
+	handler := httputils.Compose(
+		NewMiddleware(),
+		session.NewMiddleware(session.WithStore(store)),
+	)(h)
+
+	t.Run("noSession", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		NewMiddleware()(h).ServeHTTP(w, requestbuilder.Get("/").Request())
+
+		expect.That(t, is.EqualTo(w.Result().StatusCode, http.StatusInternalServerError))
+	})
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, requestbuilder.Get("/").Request())
+
+	token := w.Header().Get(DefaultHeaderName)
+	sessionCookie := w.Result().Cookies()[0]
+
+	expect.That(t,
+		is.EqualTo(w.Result().StatusCode, http.StatusOK),
+		is.EqualTo(len(token) > 0, true),
+	)
+
+	t.Run("missingToken", func(t *testing.T) {
+		r := requestbuilder.Post("/").Request()
+		r.AddCookie(sessionCookie)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		expect.That(t, is.EqualTo(w.Result().StatusCode, http.StatusForbidden))
+	})
+
+	t.Run("wrongToken", func(t *testing.T) {
+		r := requestbuilder.Post("/").AddHeader(DefaultHeaderName, "foo").Request()
+		r.AddCookie(sessionCookie)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		expect.That(t, is.EqualTo(w.Result().StatusCode, http.StatusForbidden))
+	})
+
+	t.Run("header", func(t *testing.T) {
+		r := requestbuilder.Post("/").AddHeader(DefaultHeaderName, token).Request()
+		r.AddCookie(sessionCookie)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		expect.That(t,
+			is.EqualTo(w.Result().StatusCode, http.StatusOK),
+			is.EqualTo(w.Header().Get(DefaultHeaderName), token),
+		)
+	})
+
+	t.Run("formField", func(t *testing.T) {
+		body := url.Values{DefaultFieldName: []string{token}}.Encode()
+		r := requestbuilder.Post("/").
+			AddHeader("Content-Type", "application/x-www-form-urlencoded").
+			Body(strings.NewReader(body)).
+			Request()
+		r.AddCookie(sessionCookie)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		expect.That(t, is.EqualTo(w.Result().StatusCode, http.StatusOK))
+	})
+}
+
+func TestMiddleware_doubleSubmitCookie(t *testing.T) {
+	handler := NewMiddleware(WithMode(ModeDoubleSubmitCookie))(h)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, requestbuilder.Get("/").Request())
+
+	cookies := w.Result().Cookies()
+	expect.That(t,
+		is.EqualTo(w.Result().StatusCode, http.StatusOK),
+		is.SliceOfLen(cookies, 1),
+		is.EqualTo(cookies[0].Name, DefaultCookieName),
+		is.EqualTo(cookies[0].Value, w.Header().Get(DefaultHeaderName)),
+	)
+
+	t.Run("missingCookie", func(t *testing.T) {
+		r := requestbuilder.Post("/").AddHeader(DefaultHeaderName, cookies[0].Value).Request()
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		expect.That(t, is.EqualTo(w.Result().StatusCode, http.StatusForbidden))
+	})
+
+	t.Run("match", func(t *testing.T) {
+		r := requestbuilder.Post("/").AddHeader(DefaultHeaderName, cookies[0].Value).Request()
+		r.AddCookie(cookies[0])
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		expect.That(t, is.EqualTo(w.Result().StatusCode, http.StatusOK))
+	})
+}
+
+func TestMiddleware_originCheck(t *testing.T) {
+	handler := NewMiddleware(WithMode(ModeOriginCheck), WithTrustedOrigins("https://trusted.example.com"))(h)
+
+	tab := map[string]struct {
+		req  *http.Request
+		want int
+	}{
+		"safeMethod":       {requestbuilder.Get("http://example.com/").AddHeader(HeaderSecFetchSite, "cross-site").Request(), http.StatusOK},
+		"noHeaders":        {requestbuilder.Post("http://example.com/").Request(), http.StatusOK},
+		"sameOrigin":       {requestbuilder.Post("http://example.com/").AddHeader(HeaderSecFetchSite, "same-origin").Request(), http.StatusOK},
+		"crossSite":        {requestbuilder.Post("http://example.com/").AddHeader(HeaderSecFetchSite, "cross-site").Request(), http.StatusForbidden},
+		"crossSiteTrusted": {requestbuilder.Post("http://example.com/").AddHeader(HeaderSecFetchSite, "cross-site").AddHeader(HeaderOrigin, "https://trusted.example.com").Request(), http.StatusOK},
+		"originMatches":    {requestbuilder.Post("http://example.com/").AddHeader(HeaderOrigin, "https://example.com").Request(), http.StatusOK},
+		"originMismatch":   {requestbuilder.Post("http://example.com/").AddHeader(HeaderOrigin, "https://evil.com").Request(), http.StatusForbidden},
+	}
+
+	for name, test := range tab {
+		t.Run(name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, test.req)
+			expect.That(t, is.EqualTo(w.Result().StatusCode, test.want))
+		})
+	}
+}
+
+func TestTemplateField(t *testing.T) {
+	r := requestbuilder.Get("/").Request()
+	expect.That(t, is.EqualTo(TemplateField(r), ""))
+
+	r = r.WithContext(withToken(r.Context(), tokenInfo{token: "a<b", fieldName: "_csrf"}))
+	expect.That(t, is.EqualTo(string(TemplateField(r)), `<input type="hidden" name="_csrf" value="a&lt;b">`))
+}
@@ -0,0 +1,35 @@
+package csrf_test
+
+import (
+	"html/template"
+	"net/http"
+
+	"github.com/halimath/httputils"
+	"github.com/halimath/httputils/csrf"
+	"github.com/halimath/httputils/session"
+)
+
+func Example() {
+	tpl := template.Must(template.New("form").Parse(`<form method="post">{{ .CSRF }}<button>Send</button></form>`))
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tpl.Execute(w, map[string]any{
+			"CSRF": csrf.TemplateField(r),
+		})
+	})
+
+	http.ListenAndServe(":1234", httputils.Compose(
+		csrf.NewMiddleware(),
+		session.NewMiddleware(),
+	)(handler))
+}
+
+func Example_originCheck() {
+	// restAPI is a http.Handler that defines some kind of resource.
+	restAPI := http.NewServeMux()
+
+	http.ListenAndServe(":1234", csrf.NewMiddleware(
+		csrf.WithMode(csrf.ModeOriginCheck),
+		csrf.WithTrustedOrigins("https://app.example.com"),
+	)(restAPI))
+}
@@ -4,14 +4,18 @@
 // ServeMux as defined by this package works exactly the same as [http.ServeMux]
 // with the exception, that [Handler] and [HandlerFunc] respectively return an
 // error value. Any non-nil error causes the response to be discarded and the
-// error gets handled.
+// error gets handled. Panics raised by a [Handler] are recovered and handled as
+// a [recovery.PanicError].
 package errmux
 
 import (
+	"errors"
 	"net/http"
+	"slices"
+	"strings"
 
 	"github.com/halimath/httputils/bufferedresponse"
-	"github.com/halimath/httputils/response"
+	"github.com/halimath/httputils/recovery"
 )
 
 // Handler defines an extension of [http.Handler] that returns and error value
@@ -20,7 +24,8 @@ import (
 type Handler interface {
 	// ServeHTTP serves an [http.Request] producing response to an
 	// [http.ResponseWriter]. The writer is buffered and any data written may
-	// be discarded if ServeHTTP returns a non-nil error.
+	// be discarded if ServeHTTP returns a non-nil error. See [Streaming] for
+	// routes using bounded buffering.
 	ServeHTTP(http.ResponseWriter, *http.Request) error
 }
 
@@ -37,63 +42,363 @@ func (h HandlerFunc) ServeHTTP(w http.ResponseWriter, r *http.Request) error {
 // and error handling happens unbuffered.
 type ErrorHandler func(http.ResponseWriter, *http.Request, error)
 
-// defaultErrorHandler is the default error handler which uses [response.Error]
-// to send an error.
+// defaultErrorHandler is the default error handler which uses the
+// [DefaultErrorRegistry] to send problem details in a format negotiated from
+// the request's Accept header.
 func defaultErrorHandler(w http.ResponseWriter, r *http.Request, err error) {
-	response.Error(w, r, err)
+	DefaultErrorRegistry.HandleError(w, r, err)
 }
 
 // ServeMux works like a [http.ServeMux] but with support for error-aware request
 // handling.
+//
+// Every handled error is reported to the ErrorObserver, which defaults to
+// [LogErrorObserver]. Set ErrorObserver to nil to disable error reporting.
 type ServeMux struct {
-	mux          *http.ServeMux
-	ErrorHandler ErrorHandler
+	mux           *http.ServeMux
+	routes        []*route
+	middlewares   []Middleware
+	ErrorHandler  ErrorHandler
+	ErrorObserver ErrorObserver
 }
 
 func NewServeMux() *ServeMux {
 	return &ServeMux{
-		mux:          http.NewServeMux(),
-		ErrorHandler: defaultErrorHandler,
+		mux:           http.NewServeMux(),
+		ErrorHandler:  defaultErrorHandler,
+		ErrorObserver: LogErrorObserver,
 	}
 }
 
 // decorate is used to decorate h with response writer buffering and error
-// dispatching. The resulting [http.Handler] is registered with a [http.ServeMux].
-func (mux *ServeMux) decorate(h Handler) http.Handler {
+// dispatching based on rt. The resulting [http.Handler] is registered with a
+// [http.ServeMux].
+func (mux *ServeMux) decorate(h Handler, rt *route) http.Handler {
+	if rt.streaming {
+		return mux.decorateStreaming(h, rt)
+	}
+
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		var buf bufferedresponse.ResponseWriter
-		err := h.ServeHTTP(&buf, r)
+		err := serveRecovering(h, &buf, r)
 
 		if err == nil {
 			buf.WriteTo(w)
 			return
 		}
 
-		h := mux.ErrorHandler
-		if h == nil {
-			h = defaultErrorHandler
+		mux.handleError(w, r, rt.pattern, err)
+	})
+}
+
+// decorateStreaming decorates h with bounded buffering as configured with
+// [Streaming].
+func (mux *ServeMux) decorateStreaming(h Handler, rt *route) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		buf := bufferedresponse.NewSpillingResponseWriter(w, rt.threshold)
+		err := serveRecovering(h, buf, r)
+
+		if err == nil {
+			buf.Finish()
+			return
+		}
+
+		if !buf.Spilled() {
+			mux.handleError(w, r, rt.pattern, err)
+			return
+		}
+
+		status := buf.StatusCode()
+		if status == 0 {
+			status = http.StatusOK
 		}
+		mux.observeError(r, rt.pattern, status, err, true)
 
-		h(w, r, err)
+		// A recovered panic or an error that cannot be reported as a trailer
+		// must not end in a properly terminated response, as clients would
+		// not be able to tell it from a complete one.
+		var pe *recovery.PanicError
+		if rt.errorTrailer == "" || errors.As(err, &pe) {
+			panic(http.ErrAbortHandler)
+		}
+
+		w.Header().Set(http.TrailerPrefix+rt.errorTrailer, err.Error())
 	})
 }
 
+// handleError handles err using mux's ErrorHandler and reports it to mux's
+// ErrorObserver. pattern is the pattern of the route that caused err.
+func (mux *ServeMux) handleError(w http.ResponseWriter, r *http.Request, pattern string, err error) {
+	h := mux.ErrorHandler
+	if h == nil {
+		h = defaultErrorHandler
+	}
+
+	rec := &statusRecorder{ResponseWriter: w}
+	h(rec, r, err)
+
+	status := rec.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	mux.observeError(r, pattern, status, err, false)
+}
+
+// serveRecovering invokes h and recovers from any panic raised by h. A
+// recovered panic is returned as a [recovery.PanicError]. Panics with
+// [http.ErrAbortHandler] are not recovered.
+func serveRecovering(h Handler, w http.ResponseWriter, r *http.Request) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			if v == http.ErrAbortHandler {
+				panic(v)
+			}
+			err = recovery.NewPanicError(v)
+		}
+	}()
+
+	return h.ServeHTTP(w, r)
+}
+
 // ServeHTTP dispatches the request to the handler whose
 // pattern most closely matches the request URL.
+//
+// If no route matches the request's path, [ErrNotFound] is handled by the
+// ErrorHandler. If routes match the request's path but not its method, the
+// Allow response header is set and a [*MethodNotAllowedError] is handled by
+// the ErrorHandler. OPTIONS requests for which no route matches but other
+// methods are allowed are answered with a 204 response containing the Allow
+// header. The same applies to server-wide OPTIONS * requests, for which the
+// Allow header lists the methods of all registered routes.
 func (mux *ServeMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	mux.mux.ServeHTTP(w, r)
+	if r.Method == http.MethodOptions && r.URL.Path == "*" {
+		w.Header().Set("Allow", strings.Join(append(mux.serverMethods(), http.MethodOptions), ", "))
+		w.Header().Set("Content-Length", "0")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	rw := routingWriter{ResponseWriter: w, r: r}
+	mux.mux.ServeHTTP(&rw, r)
+	if r.Pattern != "" {
+		return
+	}
+
+	allowed := mux.AllowedMethods(r)
+	if len(allowed) == 0 {
+		mux.handleError(w, r, "", ErrNotFound)
+		return
+	}
+
+	allowed = append(allowed, http.MethodOptions)
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+
+	if r.Method == http.MethodOptions {
+		w.Header().Set("Content-Length", "0")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	mux.handleError(w, r, "", &MethodNotAllowedError{Allowed: allowed})
+}
+
+// routingWriter is passed to the underlying [http.ServeMux] to route a
+// request only once. If no route matches r, the not found or method not
+// allowed response written by the http.ServeMux is discarded, so ServeHTTP
+// can handle the request using the ErrorHandler. Otherwise all writes are
+// forwarded to the wrapped ResponseWriter.
+type routingWriter struct {
+	http.ResponseWriter
+	r       *http.Request
+	discard http.Header
+}
+
+func (w *routingWriter) Header() http.Header {
+	if w.r.Pattern != "" {
+		return w.ResponseWriter.Header()
+	}
+	if w.discard == nil {
+		w.discard = make(http.Header)
+	}
+	return w.discard
+}
+
+func (w *routingWriter) Write(data []byte) (int, error) {
+	if w.r.Pattern != "" {
+		return w.ResponseWriter.Write(data)
+	}
+	return len(data), nil
 }
 
-// Handle registers the handler for the given pattern.
+func (w *routingWriter) WriteHeader(statusCode int) {
+	if w.r.Pattern != "" {
+		w.ResponseWriter.WriteHeader(statusCode)
+	}
+}
+
+func (w *routingWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+// unwrapRouting wraps h so that h receives the ResponseWriter wrapped by a
+// routingWriter.
+func unwrapRouting(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if rw, ok := w.(*routingWriter); ok {
+			w = rw.ResponseWriter
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// candidateMethods contains the methods tested when determining the allowed
+// methods for a request in addition to the methods found in registered
+// patterns.
+var candidateMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+}
+
+// AllowedMethods returns the methods for which mux has a route matching r's
+// host and path. OPTIONS is never included. The methods tested are GET, HEAD,
+// POST, PUT, PATCH and DELETE as well as any other method used in a registered
+// pattern.
+func (mux *ServeMux) AllowedMethods(r *http.Request) []string {
+	candidates := slices.Clone(candidateMethods)
+	for _, rt := range mux.routes {
+		if rt.info.Method != "" && !slices.Contains(candidates, rt.info.Method) {
+			candidates = append(candidates, rt.info.Method)
+		}
+	}
+
+	var methods []string
+	for _, m := range candidates {
+		if m == http.MethodOptions {
+			continue
+		}
+
+		req := *r
+		req.Method = m
+		if _, pattern := mux.mux.Handler(&req); pattern != "" {
+			methods = append(methods, m)
+		}
+	}
+
+	return methods
+}
+
+// serverMethods returns the methods used by any of mux's routes excluding
+// OPTIONS. Routes registered without a method contribute all candidate
+// methods.
+func (mux *ServeMux) serverMethods() []string {
+	var methods []string
+	add := func(m string) {
+		if m != http.MethodOptions && !slices.Contains(methods, m) {
+			methods = append(methods, m)
+		}
+	}
+
+	for _, rt := range mux.routes {
+		switch rt.info.Method {
+		case "":
+			for _, m := range candidateMethods {
+				add(m)
+			}
+		case http.MethodGet:
+			add(http.MethodGet)
+			add(http.MethodHead)
+		default:
+			add(rt.info.Method)
+		}
+	}
+
+	return methods
+}
+
+// Handler returns the handler to use for the given request, consulting
+// r.Method, r.Host, and r.URL.Path. It works the same way as
+// [http.ServeMux.Handler] and returns the decorated [http.Handler] as well as
+// the registered pattern that matches r.
+func (mux *ServeMux) Handler(r *http.Request) (h http.Handler, pattern string) {
+	return mux.mux.Handler(r)
+}
+
+// Patterns returns the patterns of all handlers registered with mux in the
+// order they have been registered.
+func (mux *ServeMux) Patterns() []string {
+	patterns := make([]string, len(mux.routes))
+	for i, rt := range mux.routes {
+		patterns[i] = rt.pattern
+	}
+	return patterns
+}
+
+// Routes returns information about all routes registered with mux in the order
+// they have been registered.
+func (mux *ServeMux) Routes() []RouteInfo {
+	routes := make([]RouteInfo, len(mux.routes))
+	for i, rt := range mux.routes {
+		routes[i] = rt.info
+		routes[i].Tags = slices.Clone(rt.info.Tags)
+		routes[i].Responses = slices.Clone(rt.info.Responses)
+		routes[i].Security = slices.Clone(rt.info.Security)
+	}
+	return routes
+}
+
+// Use adds middlewares to mux. The middlewares are applied to all handlers
+// registered with mux (including handlers registered with a [Group]) after Use
+// has been called. Handlers registered before are not affected. Like
+// [httputils.Compose], middlewares are given in inner-to-outer order.
+func (mux *ServeMux) Use(middlewares ...Middleware) {
+	mux.middlewares = append(mux.middlewares, middlewares...)
+}
+
+// Group creates a new [Group] with prefix, which registers handlers with mux.
+// middlewares are applied to all handlers registered with the group.
+func (mux *ServeMux) Group(prefix string, middlewares ...Middleware) *Group {
+	return &Group{
+		mux:         mux,
+		prefix:      prefix,
+		middlewares: slices.Clone(middlewares),
+	}
+}
+
+// Handle registers the handler for the given pattern. opts may customize the
+// route.
 // If the given pattern conflicts, with one that is already registered, Handle
 // panics.
-func (mux *ServeMux) Handle(pattern string, handler Handler) {
-	mux.mux.Handle(pattern, mux.decorate(handler))
+func (mux *ServeMux) Handle(pattern string, handler Handler, opts ...RouteOption) {
+	mux.handle(pattern, handler, nil, opts)
+}
+
+// handle registers handler for pattern. middlewares are applied to handler
+// before the middlewares registered with mux.
+func (mux *ServeMux) handle(pattern string, handler Handler, middlewares []Middleware, opts []RouteOption) {
+	rt := &route{pattern: pattern}
+	rt.info.Pattern = pattern
+	rt.info.Method, rt.info.Host, rt.info.Path = parsePattern(pattern)
+
+	if d, ok := handler.(routeDescriber); ok {
+		d.describeRoute(&rt.info)
+		rt.describedResponses = len(rt.info.Responses) > 0
+	}
+
+	for _, opt := range opts {
+		opt(rt)
+	}
+
+	handler = applyMiddlewares(applyMiddlewares(handler, middlewares), mux.middlewares)
+	mux.mux.Handle(pattern, unwrapRouting(mux.decorate(handler, rt)))
+	mux.routes = append(mux.routes, rt)
 }
 
-// HandleFunc registers the handler function for the given pattern.
+// HandleFunc registers the handler function for the given pattern. opts may
+// customize the route.
 // If the given pattern conflicts, with one that is already registered, HandleFunc
 // panics.
-func (mux *ServeMux) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request) error) {
-	mux.Handle(pattern, HandlerFunc(handler))
+func (mux *ServeMux) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request) error, opts ...RouteOption) {
+	mux.Handle(pattern, HandlerFunc(handler), opts...)
 }
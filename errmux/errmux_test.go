@@ -1,13 +1,17 @@
 package errmux
 
 import (
+	"context"
 	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"testing"
 
 	"github.com/halimath/expect"
 	"github.com/halimath/expect/is"
+	"github.com/halimath/httputils/recovery"
 	"github.com/halimath/httputils/requestbuilder"
 )
 
@@ -49,3 +53,263 @@ func TestServeMux(t *testing.T) {
 		is.EqualTo(recorder.Result().StatusCode, http.StatusNotImplemented),
 	)
 }
+
+func TestServeMux_Patterns(t *testing.T) {
+	mux := NewServeMux()
+	noop := func(http.ResponseWriter, *http.Request) error { return nil }
+	mux.HandleFunc("GET /orders/{id}", noop)
+	mux.HandleFunc("/", noop)
+
+	_, pattern := mux.Handler(requestbuilder.Get("/orders/17").Request())
+
+	expect.That(t,
+		is.DeepEqualTo(mux.Patterns(), []string{"GET /orders/{id}", "/"}),
+		is.EqualTo(pattern, "GET /orders/{id}"),
+	)
+}
+
+func TestServeMux_AllowedMethods(t *testing.T) {
+	mux := NewServeMux()
+	noop := func(http.ResponseWriter, *http.Request) error { return nil }
+	mux.HandleFunc("GET /orders/{id}", noop)
+	mux.HandleFunc("PUT /orders/{id}", noop)
+	mux.HandleFunc("PURGE /orders/{id}", noop)
+	mux.HandleFunc("OPTIONS /orders/{id}", noop)
+
+	expect.That(t,
+		is.DeepEqualTo(mux.AllowedMethods(requestbuilder.Get("/orders/17").Request()), []string{http.MethodGet, http.MethodHead, http.MethodPut, "PURGE"}),
+		is.SliceOfLen(mux.AllowedMethods(requestbuilder.Get("/customers/17").Request()), 0),
+	)
+}
+
+func TestServeMux_Routes(t *testing.T) {
+	type order struct {
+		ID int `json:"id"`
+	}
+
+	mux := NewServeMux()
+	api := mux.Group("/api", func(h Handler) Handler { return h })
+	api.Handle("GET /orders/{id}", Typed(func(ctx context.Context, req struct{}) (order, error) { return order{}, nil }),
+		Summary("Get an order"),
+		Tags("orders"),
+	)
+	mux.HandleFunc("example.com/", func(http.ResponseWriter, *http.Request) error { return nil },
+		Response[string](http.StatusOK, "Greeting"),
+		Hidden(),
+	)
+
+	routes := mux.Routes()
+	expect.That(t, expect.FailNow(is.SliceOfLen(routes, 2)))
+
+	// reflect.Type values cannot be compared deeply; compare them separately
+	expect.That(t,
+		is.EqualTo(routes[0].Request, reflect.TypeFor[struct{}]()),
+		is.EqualTo(routes[0].Responses[0].Type, reflect.TypeFor[order]()),
+		is.EqualTo(routes[1].Responses[0].Type, reflect.TypeFor[string]()),
+	)
+	routes[0].Request, routes[0].Responses[0].Type, routes[1].Responses[0].Type = nil, nil, nil
+
+	expect.That(t, is.DeepEqualTo(routes, []RouteInfo{
+		{
+			Pattern:   "GET /api/orders/{id}",
+			Method:    "GET",
+			Path:      "/api/orders/{id}",
+			Summary:   "Get an order",
+			Tags:      []string{"orders"},
+			Responses: []ResponseInfo{{Status: http.StatusOK, Description: "OK"}},
+		},
+		{
+			Pattern:   "example.com/",
+			Host:      "example.com",
+			Path:      "/",
+			Hidden:    true,
+			Responses: []ResponseInfo{{Status: http.StatusOK, Description: "Greeting"}},
+		},
+	}))
+}
+
+func TestServeMux_notFound(t *testing.T) {
+	var handledErr error
+
+	mux := NewServeMux()
+	mux.HandleFunc("GET /orders/{id}", func(http.ResponseWriter, *http.Request) error { return nil })
+	mux.HandleFunc("DELETE /orders/{id}", func(http.ResponseWriter, *http.Request) error { return nil })
+	mux.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		handledErr = err
+		DefaultErrorRegistry.HandleError(w, r, err)
+	}
+
+	t.Run("not_found", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, requestbuilder.Get("/customers/1").Request())
+
+		expect.That(t,
+			is.Error(handledErr, ErrNotFound),
+			is.EqualTo(w.Code, http.StatusNotFound),
+			is.EqualTo(w.Header().Get("Content-Type"), "application/problem+json"),
+		)
+	})
+
+	t.Run("method_not_allowed", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, requestbuilder.Post("/orders/1").Request())
+
+		var mnaErr *MethodNotAllowedError
+		expect.That(t,
+			is.Error(handledErr, ErrMethodNotAllowed),
+			is.EqualTo(errors.As(handledErr, &mnaErr), true),
+			is.DeepEqualTo(mnaErr.Allowed, []string{"GET", "HEAD", "DELETE", "OPTIONS"}),
+			is.EqualTo(w.Code, http.StatusMethodNotAllowed),
+			is.EqualTo(w.Header().Get("Allow"), "GET, HEAD, DELETE, OPTIONS"),
+			is.EqualTo(w.Header().Get("Content-Type"), "application/problem+json"),
+		)
+	})
+
+	t.Run("options", func(t *testing.T) {
+		handledErr = nil
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, requestbuilder.Options("/orders/1").Request())
+
+		expect.That(t,
+			is.NoError(handledErr),
+			is.EqualTo(w.Code, http.StatusNoContent),
+			is.EqualTo(w.Header().Get("Allow"), "GET, HEAD, DELETE, OPTIONS"),
+		)
+	})
+
+	t.Run("options_asterisk", func(t *testing.T) {
+		handledErr = nil
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodOptions, "*", nil))
+
+		expect.That(t,
+			is.NoError(handledErr),
+			is.EqualTo(w.Code, http.StatusNoContent),
+			is.EqualTo(w.Header().Get("Allow"), "GET, HEAD, DELETE, OPTIONS"),
+		)
+	})
+}
+
+func TestServeMux_redirect(t *testing.T) {
+	mux := NewServeMux()
+	mux.HandleFunc("GET /orders/", func(http.ResponseWriter, *http.Request) error { return nil })
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, requestbuilder.Get("/orders").Request())
+
+	expect.That(t,
+		is.EqualTo(w.Code, http.StatusTemporaryRedirect),
+		is.EqualTo(w.Header().Get("Location"), "/orders/"),
+	)
+}
+
+func TestServeMux_panic(t *testing.T) {
+	mux := NewServeMux()
+
+	var handledError error
+	mux.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		handledError = err
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, _ *http.Request) error {
+		w.WriteHeader(http.StatusOK)
+		panic("kaboom")
+	})
+
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, requestbuilder.Get("/").Request())
+
+	var pe *recovery.PanicError
+	expect.That(t,
+		is.EqualTo(recorder.Result().StatusCode, http.StatusInternalServerError),
+		is.EqualTo(errors.As(handledError, &pe), true),
+		is.EqualTo(pe.Value, any("kaboom")),
+	)
+}
+
+func TestServeMux_Streaming(t *testing.T) {
+	errKaboom := errors.New("kaboom")
+
+	mux := NewServeMux()
+	mux.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+
+	mux.HandleFunc("/stream", func(w http.ResponseWriter, r *http.Request) error {
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, "data: 1\n\n")
+		if err := http.NewResponseController(w).Flush(); err != nil {
+			return err
+		}
+
+		if r.URL.Query().Get("fail") == "true" {
+			return errKaboom
+		}
+
+		io.WriteString(w, "data: 2\n\n")
+		return nil
+	}, Streaming(1024), ErrorTrailer("X-Error"))
+
+	mux.HandleFunc("/small", func(w http.ResponseWriter, r *http.Request) error {
+		io.WriteString(w, "hello")
+		return errKaboom
+	}, Streaming(1024))
+
+	mux.HandleFunc("/noTrailer", func(w http.ResponseWriter, r *http.Request) error {
+		io.WriteString(w, "data: 1\n\n")
+		http.NewResponseController(w).Flush()
+		return errKaboom
+	}, Streaming(1024))
+
+	mux.HandleFunc("/panic", func(w http.ResponseWriter, r *http.Request) error {
+		io.WriteString(w, "data: 1\n\n")
+		http.NewResponseController(w).Flush()
+		panic("kaboom")
+	}, Streaming(1024), ErrorTrailer("X-Error"))
+
+	t.Run("success", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, requestbuilder.Get("/stream").Request())
+
+		expect.That(t,
+			is.EqualTo(w.Flushed, true),
+			is.EqualTo(w.Result().StatusCode, http.StatusOK),
+			is.EqualTo(w.Body.String(), "data: 1\n\ndata: 2\n\n"),
+		)
+	})
+
+	t.Run("errorAfterFlush", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, requestbuilder.Get("/stream?fail=true").Request())
+
+		res := w.Result()
+		expect.That(t,
+			is.EqualTo(res.StatusCode, http.StatusOK),
+			is.EqualTo(w.Body.String(), "data: 1\n\n"),
+			is.EqualTo(res.Trailer.Get("X-Error"), "kaboom"),
+		)
+	})
+
+	for _, path := range []string{"/noTrailer", "/panic"} {
+		t.Run("abort"+path, func(t *testing.T) {
+			var recovered any
+			func() {
+				defer func() { recovered = recover() }()
+				mux.ServeHTTP(httptest.NewRecorder(), requestbuilder.Get(path).Request())
+			}()
+
+			expect.That(t, is.EqualTo(recovered, any(http.ErrAbortHandler)))
+		})
+	}
+
+	t.Run("errorBeforeSpill", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, requestbuilder.Get("/small").Request())
+
+		expect.That(t,
+			is.EqualTo(w.Result().StatusCode, http.StatusInternalServerError),
+			is.EqualTo(w.Body.String(), ""),
+		)
+	})
+}
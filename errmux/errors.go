@@ -0,0 +1,270 @@
+package errmux
+
+import (
+	"context"
+	"errors"
+	"html/template"
+	"net/http"
+	"strings"
+
+	"github.com/halimath/httputils/requestid"
+	"github.com/halimath/httputils/response"
+	"github.com/halimath/httputils/session"
+)
+
+// HTTPError defines an interface for errors that carry their own HTTP response
+// details. When an HTTPError is handled by an [ErrorRegistry] the status code,
+// headers and problem details are taken from the error.
+type HTTPError interface {
+	error
+
+	// StatusCode returns the HTTP status code to send.
+	StatusCode() int
+
+	// Header returns additional response headers to send. It may return nil.
+	Header() http.Header
+
+	// ProblemDetails returns the problem details to send as the response'
+	// body. A zero Status is replaced with StatusCode.
+	ProblemDetails() response.ProblemDetails
+}
+
+// StatusError is a ready-to-use implementation of [HTTPError] that wraps
+// another error.
+type StatusError struct {
+	// Status defines the HTTP status code to send.
+	Status int
+
+	// Headers defines additional response headers - optional.
+	Headers http.Header
+
+	// Details defines the problem details to send - optional. If left empty,
+	// problem details are created from Status.
+	Details response.ProblemDetails
+
+	// Err is the wrapped error - optional.
+	Err error
+}
+
+// NewStatusError creates a new [StatusError] with status wrapping err.
+func NewStatusError(status int, err error) *StatusError {
+	return &StatusError{
+		Status: status,
+		Err:    err,
+	}
+}
+
+func (e *StatusError) Error() string {
+	if e.Err != nil {
+		return e.Err.Error()
+	}
+	return http.StatusText(e.Status)
+}
+
+func (e *StatusError) Unwrap() error { return e.Err }
+
+func (e *StatusError) StatusCode() int { return e.Status }
+
+func (e *StatusError) Header() http.Header { return e.Headers }
+
+func (e *StatusError) ProblemDetails() response.ProblemDetails { return e.Details }
+
+// --
+
+var (
+	// ErrNotFound is handled by a [ServeMux]'s ErrorHandler when no route
+	// matches a request. Handlers may return ErrNotFound as well.
+	ErrNotFound = errors.New("not found")
+
+	// ErrMethodNotAllowed is handled by a [ServeMux]'s ErrorHandler when
+	// routes match a request's path but not its method. The error passed to
+	// the ErrorHandler is a [*MethodNotAllowedError] which matches
+	// ErrMethodNotAllowed using [errors.Is].
+	ErrMethodNotAllowed = errors.New("method not allowed")
+)
+
+// MethodNotAllowedError is handled by a [ServeMux]'s ErrorHandler when routes
+// match a request's path but not its method. It carries the allowed methods.
+type MethodNotAllowedError struct {
+	// Allowed contains the methods allowed for the requested resource.
+	Allowed []string
+}
+
+func (e *MethodNotAllowedError) Error() string {
+	return ErrMethodNotAllowed.Error() + " (allowed: " + strings.Join(e.Allowed, ", ") + ")"
+}
+
+// Is reports whether target is [ErrMethodNotAllowed].
+func (e *MethodNotAllowedError) Is(target error) bool { return target == ErrMethodNotAllowed }
+
+// --
+
+// ErrorMapper defines a function type that maps an error to a
+// [response.ProblemDetails]. It returns false if err is not handled by the
+// mapper. The returned problem details' Status defines the HTTP status code to
+// send.
+type ErrorMapper func(err error) (response.ProblemDetails, bool)
+
+// StatusClientClosedRequest is a non-standard HTTP status code used to signal
+// that a client has closed the connection before the response was sent.
+const StatusClientClosedRequest = 499
+
+// ErrorRegistry maps errors to HTTP responses. Mappers are consulted in reverse
+// order of registration so that mappers registered later take precedence over
+// mappers registered earlier (including the built-in ones). Errors that
+// implement [HTTPError] take precedence over all mappers.
+//
+// An ErrorRegistry is not safe for concurrent modification; register all
+// mappings before handling requests.
+type ErrorRegistry struct {
+	// ErrorPageTemplate is used to render HTML error pages - optional. If
+	// nil, DefaultErrorPageTemplate is used.
+	ErrorPageTemplate *template.Template
+
+	mappers []ErrorMapper
+}
+
+// NewErrorRegistry creates a new ErrorRegistry containing the following
+// built-in mappings:
+//
+//   - [context.Canceled] maps to [StatusClientClosedRequest]
+//   - [context.DeadlineExceeded] maps to [http.StatusGatewayTimeout]
+//   - [http.MaxBytesError] maps to [http.StatusRequestEntityTooLarge]
+//   - [session.ErrSessionNotFound] maps to [http.StatusUnauthorized]
+//   - [DecodeError] maps to [http.StatusBadRequest]
+//   - [ErrNotFound] maps to [http.StatusNotFound]
+//   - [ErrMethodNotAllowed] maps to [http.StatusMethodNotAllowed]
+//   - [response.ErrInvalidCursor] maps to [http.StatusBadRequest]
+//   - [response.ErrPreconditionFailed] maps to [http.StatusPreconditionFailed]
+//   - [response.ErrPreconditionRequired] maps to [http.StatusPreconditionRequired]
+func NewErrorRegistry() *ErrorRegistry {
+	reg := &ErrorRegistry{}
+
+	reg.MapError(context.Canceled, response.ProblemDetails{
+		Status: StatusClientClosedRequest,
+		Title:  "Client Closed Request",
+	})
+	reg.MapError(context.DeadlineExceeded, response.ProblemDetails{Status: http.StatusGatewayTimeout})
+	MapErrorType(reg, func(*http.MaxBytesError) response.ProblemDetails {
+		return response.ProblemDetails{Status: http.StatusRequestEntityTooLarge}
+	})
+	reg.MapError(session.ErrSessionNotFound, response.ProblemDetails{Status: http.StatusUnauthorized})
+	MapErrorType(reg, func(err *DecodeError) response.ProblemDetails {
+		return response.ProblemDetails{
+			Status: http.StatusBadRequest,
+			Detail: err.Error(),
+		}
+	})
+	reg.MapError(ErrNotFound, response.ProblemDetails{Status: http.StatusNotFound})
+	reg.MapError(ErrMethodNotAllowed, response.ProblemDetails{Status: http.StatusMethodNotAllowed})
+	reg.MapError(response.ErrInvalidCursor, response.ProblemDetails{Status: http.StatusBadRequest})
+	reg.MapError(response.ErrPreconditionFailed, response.ProblemDetails{Status: http.StatusPreconditionFailed})
+	reg.MapError(response.ErrPreconditionRequired, response.ProblemDetails{Status: http.StatusPreconditionRequired})
+
+	return reg
+}
+
+// DefaultErrorRegistry is the [ErrorRegistry] used by the default error
+// handler of a [ServeMux].
+var DefaultErrorRegistry = NewErrorRegistry()
+
+// Map registers m with reg.
+func (reg *ErrorRegistry) Map(m ErrorMapper) {
+	reg.mappers = append(reg.mappers, m)
+}
+
+// MapError registers a mapping for all errors that match target using
+// [errors.Is].
+func (reg *ErrorRegistry) MapError(target error, pd response.ProblemDetails) {
+	reg.Map(func(err error) (response.ProblemDetails, bool) {
+		if errors.Is(err, target) {
+			return pd, true
+		}
+		return response.ProblemDetails{}, false
+	})
+}
+
+// MapErrorType registers a mapping for all errors of type E using [errors.As].
+// f is invoked with the matched error to create the problem details.
+func MapErrorType[E error](reg *ErrorRegistry, f func(E) response.ProblemDetails) {
+	reg.Map(func(err error) (response.ProblemDetails, bool) {
+		var target E
+		if errors.As(err, &target) {
+			return f(target), true
+		}
+		return response.ProblemDetails{}, false
+	})
+}
+
+// Resolve resolves err to problem details and additional response headers.
+// It returns false if err is not mapped by reg. The problem details returned
+// always contain a non-zero Status as well as a Type and Title.
+func (reg *ErrorRegistry) Resolve(err error) (response.ProblemDetails, http.Header, bool) {
+	var httpErr HTTPError
+	if errors.As(err, &httpErr) {
+		pd := httpErr.ProblemDetails()
+		if pd.Status == 0 {
+			pd.Status = httpErr.StatusCode()
+		}
+		return completeProblemDetails(pd), httpErr.Header(), true
+	}
+
+	for i := len(reg.mappers) - 1; i >= 0; i-- {
+		if pd, ok := reg.mappers[i](err); ok {
+			return completeProblemDetails(pd), nil, true
+		}
+	}
+
+	return response.ProblemDetails{}, nil, false
+}
+
+// HandleError handles err by sending problem details resolved from reg.
+// Errors not mapped by reg are sent as a 500 response; if the request's
+// [response.Config] enables ErrorDetails (i.e. if [response.DevMode] is
+// enabled), the response contains the error's description. If the request
+// carries an ID (see [requestid.NewMiddleware]) and the problem details define
+// no Instance, the request ID is used as Instance so clients can refer to it
+// when reporting the problem. The response's
+// format is negotiated based on the request's Accept header: problem details
+// are sent as application/problem+json (the default),
+// application/problem+xml, text/html using reg's ErrorPageTemplate or
+// text/plain.
+//
+// HandleError satisfies [ErrorHandler] and can be used as a [ServeMux]'s
+// ErrorHandler.
+func (reg *ErrorRegistry) HandleError(w http.ResponseWriter, r *http.Request, err error) {
+	pd, header, ok := reg.Resolve(err)
+	if !ok {
+		pd = unmappedProblemDetails(r, err)
+	}
+
+	if pd.Instance == "" {
+		pd.Instance = requestid.FromContext(r.Context())
+	}
+
+	for k, vals := range header {
+		for _, v := range vals {
+			w.Header().Add(k, v)
+		}
+	}
+
+	reg.render(w, r, pd)
+}
+
+// completeProblemDetails fills the required fields of pd with defaults derived
+// from pd's status.
+func completeProblemDetails(pd response.ProblemDetails) response.ProblemDetails {
+	if pd.Status == 0 {
+		pd.Status = http.StatusInternalServerError
+	}
+
+	if pd.Type == "" {
+		pd.Type = "about:blank"
+	}
+
+	if pd.Title == "" {
+		pd.Title = http.StatusText(pd.Status)
+	}
+
+	return pd
+}
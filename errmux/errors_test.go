@@ -0,0 +1,192 @@
+package errmux
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/halimath/expect"
+	"github.com/halimath/expect/is"
+	"github.com/halimath/httputils/requestbuilder"
+	"github.com/halimath/httputils/response"
+	"github.com/halimath/httputils/session"
+)
+
+type validationError struct {
+	field string
+}
+
+func (e *validationError) Error() string { return "invalid field: " + e.field }
+
+func TestErrorRegistry_Resolve(t *testing.T) {
+	errConflict := errors.New("conflict")
+
+	reg := NewErrorRegistry()
+	reg.MapError(errConflict, response.ProblemDetails{
+		Type:   "https://example.com/problems/conflict",
+		Status: http.StatusConflict,
+	})
+	MapErrorType(reg, func(e *validationError) response.ProblemDetails {
+		return response.ProblemDetails{Status: http.StatusBadRequest, Detail: e.Error()}
+	})
+
+	tab := map[string]struct {
+		err  error
+		want response.ProblemDetails
+		ok   bool
+	}{
+		"unmapped": {errors.New("kaboom"), response.ProblemDetails{}, false},
+		"sentinel": {fmt.Errorf("wrapped: %w", errConflict), response.ProblemDetails{
+			Type:   "https://example.com/problems/conflict",
+			Title:  "Conflict",
+			Status: http.StatusConflict,
+		}, true},
+		"type": {fmt.Errorf("wrapped: %w", &validationError{"name"}), response.ProblemDetails{
+			Type:   "about:blank",
+			Title:  "Bad Request",
+			Status: http.StatusBadRequest,
+			Detail: "invalid field: name",
+		}, true},
+		"canceled": {context.Canceled, response.ProblemDetails{
+			Type:   "about:blank",
+			Title:  "Client Closed Request",
+			Status: StatusClientClosedRequest,
+		}, true},
+		"maxBytes": {&http.MaxBytesError{Limit: 10}, response.ProblemDetails{
+			Type:   "about:blank",
+			Title:  "Request Entity Too Large",
+			Status: http.StatusRequestEntityTooLarge,
+		}, true},
+		"sessionNotFound": {session.ErrSessionNotFound, response.ProblemDetails{
+			Type:   "about:blank",
+			Title:  "Unauthorized",
+			Status: http.StatusUnauthorized,
+		}, true},
+		"invalidCursor": {fmt.Errorf("%w: malformed", response.ErrInvalidCursor), response.ProblemDetails{
+			Type:   "about:blank",
+			Title:  "Bad Request",
+			Status: http.StatusBadRequest,
+		}, true},
+		"preconditionFailed": {response.ErrPreconditionFailed, response.ProblemDetails{
+			Type:   "about:blank",
+			Title:  "Precondition Failed",
+			Status: http.StatusPreconditionFailed,
+		}, true},
+		"pageRequestError": {&response.PageRequestError{Param: "limit", Detail: "must be a positive integer"}, response.ProblemDetails{
+			Type:   "about:blank",
+			Title:  "Bad Request",
+			Status: http.StatusBadRequest,
+			Detail: "invalid query parameter limit: must be a positive integer",
+		}, true},
+		"httpError": {fmt.Errorf("wrapped: %w", NewStatusError(http.StatusTeapot, errConflict)), response.ProblemDetails{
+			Type:   "about:blank",
+			Title:  "I'm a teapot",
+			Status: http.StatusTeapot,
+		}, true},
+	}
+
+	for name, test := range tab {
+		t.Run(name, func(t *testing.T) {
+			got, _, ok := reg.Resolve(test.err)
+			expect.That(t,
+				is.EqualTo(ok, test.ok),
+				is.DeepEqualTo(got, test.want),
+			)
+		})
+	}
+}
+
+func TestErrorRegistry_HandleError(t *testing.T) {
+	mux := NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) error {
+		return &StatusError{
+			Status:  http.StatusTooManyRequests,
+			Headers: http.Header{"Retry-After": []string{"120"}},
+			Details: response.ProblemDetails{Detail: "slow down"},
+		}
+	})
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, requestbuilder.Get("/").Request())
+
+	expect.That(t,
+		is.EqualTo(w.Result().StatusCode, http.StatusTooManyRequests),
+		is.EqualTo(w.Header().Get("Retry-After"), "120"),
+		is.EqualTo(w.Header().Get("Content-Type"), "application/problem+json"),
+		is.EqualTo(strings.TrimSpace(w.Body.String()), `{"type":"about:blank","title":"Too Many Requests","status":429,"detail":"slow down"}`),
+	)
+}
+
+func TestErrorRegistry_HandleError_negotiation(t *testing.T) {
+	reg := NewErrorRegistry()
+	reg.MapError(ErrNotFound, response.ProblemDetails{Status: http.StatusNotFound, Detail: "no such order"})
+
+	tests := map[string]struct {
+		accept      string
+		contentType string
+		body        string
+	}{
+		"none": {
+			accept:      "",
+			contentType: "application/problem+json",
+			body:        `{"type":"about:blank","title":"Not Found","status":404,"detail":"no such order"}`,
+		},
+		"json": {
+			accept:      "application/json",
+			contentType: "application/problem+json",
+			body:        `{"type":"about:blank","title":"Not Found","status":404,"detail":"no such order"}`,
+		},
+		"xml": {
+			accept:      "application/problem+xml",
+			contentType: "application/problem+xml",
+			body:        `<?xml version="1.0" encoding="UTF-8"?>` + "\n" + `<problem xmlns="urn:ietf:rfc:7807"><type>about:blank</type><title>Not Found</title><status>404</status><detail>no such order</detail></problem>`,
+		},
+		"browser": {
+			accept:      "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8",
+			contentType: "text/html; charset=utf-8",
+			body:        "<h1>404 Not Found</h1>",
+		},
+		"text": {
+			accept:      "text/plain",
+			contentType: "text/plain",
+			body:        "404 Not Found\n\nno such order",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := requestbuilder.Get("/orders/1")
+			if test.accept != "" {
+				r.AddHeader("Accept", test.accept)
+			}
+
+			reg.HandleError(w, r.Request(), ErrNotFound)
+
+			expect.That(t,
+				is.EqualTo(w.Code, http.StatusNotFound),
+				is.EqualTo(w.Header().Get("Content-Type"), test.contentType),
+				is.EqualTo(w.Header().Get("Vary"), "Accept"),
+				is.StringContaining(w.Body.String(), test.body),
+			)
+		})
+	}
+}
+
+func TestErrorRegistry_HandleError_template(t *testing.T) {
+	reg := NewErrorRegistry()
+	reg.ErrorPageTemplate = template.Must(template.New("error").Parse(`<p>{{ .Title }}</p>`))
+
+	w := httptest.NewRecorder()
+	reg.HandleError(w, requestbuilder.Get("/").AddHeader("Accept", "text/html").Request(), errors.New("kaboom"))
+
+	expect.That(t,
+		is.EqualTo(w.Code, http.StatusInternalServerError),
+		is.EqualTo(w.Body.String(), "<p>Internal Server Error</p>"),
+	)
+}
@@ -1,6 +1,7 @@
 package errmux_test
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
@@ -33,3 +34,24 @@ func ExampleServeMux() {
 
 	http.ListenAndServe(":8080", mux)
 }
+
+func ExampleTyped() {
+	type getOrderRequest struct {
+		ID     int    `path:"id"`
+		Expand bool   `query:"expand"`
+		Tenant string `header:"X-Tenant"`
+	}
+
+	type order struct {
+		ID     int    `json:"id"`
+		Tenant string `json:"tenant"`
+	}
+
+	mux := errmux.NewServeMux()
+
+	mux.Handle("GET /orders/{id}", errmux.Typed(func(ctx context.Context, req getOrderRequest) (order, error) {
+		return order{ID: req.ID, Tenant: req.Tenant}, nil
+	}))
+
+	http.ListenAndServe(":8080", mux)
+}
@@ -0,0 +1,204 @@
+package errmux
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/halimath/httputils"
+	"github.com/halimath/httputils/bufferedresponse"
+)
+
+// Middleware defines the function signature for error-aware middlewares. In
+// contrast to [httputils.Middleware], a Middleware receives the error returned
+// from downstream handlers and may return errors itself.
+type Middleware = func(Handler) Handler
+
+// applyMiddlewares applies middlewares to h in inner-to-outer order, so
+// middlewares[0] is applied first.
+func applyMiddlewares(h Handler, middlewares []Middleware) Handler {
+	for _, mw := range middlewares {
+		h = mw(h)
+	}
+	return h
+}
+
+// Group registers handlers with a [ServeMux] using a shared pattern prefix and
+// shared middlewares. Create a Group using [ServeMux.Group].
+type Group struct {
+	mux         *ServeMux
+	prefix      string
+	middlewares []Middleware
+}
+
+// Use adds middlewares to g. The middlewares are applied to all handlers
+// registered with g after Use has been called. Middlewares added to g are
+// applied inside the middlewares added to the [ServeMux].
+func (g *Group) Use(middlewares ...Middleware) {
+	g.middlewares = append(g.middlewares, middlewares...)
+}
+
+// Group creates a nested group which uses g's prefix joined with prefix and
+// applies middlewares inside of g's middlewares.
+func (g *Group) Group(prefix string, middlewares ...Middleware) *Group {
+	return &Group{
+		mux:         g.mux,
+		prefix:      joinPath(g.prefix, prefix),
+		middlewares: append(slices.Clone(middlewares), g.middlewares...),
+	}
+}
+
+// Handle registers handler for pattern prefixed with g's prefix. pattern may
+// contain a method but must not contain a host. opts may customize the route. If the resulting pattern
+// conflicts with one that is already registered, Handle panics.
+func (g *Group) Handle(pattern string, handler Handler, opts ...RouteOption) {
+	g.mux.handle(g.pattern(pattern), handler, g.middlewares, opts)
+}
+
+// HandleFunc registers the handler function for pattern prefixed with g's
+// prefix. See [Group.Handle] for details.
+func (g *Group) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request) error, opts ...RouteOption) {
+	g.Handle(pattern, HandlerFunc(handler), opts...)
+}
+
+// pattern joins g's prefix with pattern.
+func (g *Group) pattern(pattern string) string {
+	method, path, ok := strings.Cut(pattern, " ")
+	if !ok {
+		method, path = "", pattern
+	}
+	path = strings.TrimLeft(path, " \t")
+
+	if !strings.HasPrefix(path, "/") {
+		panic(fmt.Sprintf("errmux: group pattern %q must start with a path", pattern))
+	}
+
+	path = joinPath(g.prefix, path)
+
+	if method == "" {
+		return path
+	}
+	return method + " " + path
+}
+
+// joinPath joins prefix and path avoiding duplicate slashes.
+func joinPath(prefix, path string) string {
+	if path == "" {
+		return prefix
+	}
+	return strings.TrimSuffix(prefix, "/") + path
+}
+
+// --
+
+// Private type for the context key
+type contextKeyType string
+
+// Sentinel value used as the context key to hold the errorCarrier of an
+// adapted middleware.
+const errorCarrierKey contextKeyType = "errorCarrier"
+
+// errorCarrier carries the error returned from the downstream handler of an
+// adapted middleware back to the adapter. It is passed down both as the
+// ResponseWriter and as a context value, so it can be found even if the
+// middleware replaces one of them.
+type errorCarrier struct {
+	http.ResponseWriter
+	mu  sync.Mutex
+	err error
+}
+
+func (c *errorCarrier) Unwrap() http.ResponseWriter { return c.ResponseWriter }
+
+func (c *errorCarrier) Flush() {
+	http.NewResponseController(c.ResponseWriter).Flush()
+}
+
+func (c *errorCarrier) setErr(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.err = err
+}
+
+func (c *errorCarrier) result() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// findErrorCarrier returns the errorCarrier wrapped by w or stored in r's
+// context or nil, if neither holds one.
+func findErrorCarrier(w http.ResponseWriter, r *http.Request) *errorCarrier {
+	for w != nil {
+		if c, ok := w.(*errorCarrier); ok {
+			return c
+		}
+
+		u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			break
+		}
+		w = u.Unwrap()
+	}
+
+	c, _ := r.Context().Value(errorCarrierKey).(*errorCarrier)
+	return c
+}
+
+// FromHTTPMiddleware adapts mw to an error-aware [Middleware]. This allows
+// existing middlewares, such as the ones provided by the cors, auth or session
+// packages, to be used with [ServeMux.Use] and [ServeMux.Group]. Errors
+// returned from downstream handlers are passed through mw and returned.
+//
+// If mw replaces both the ResponseWriter (without providing an Unwrap method)
+// and the request's context, a downstream error can not be returned and is
+// handled using the default error handler instead.
+func FromHTTPMiddleware(mw httputils.Middleware) Middleware {
+	return func(next Handler) Handler {
+		wrapped := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			c := findErrorCarrier(w, r)
+			err := next.ServeHTTP(w, r)
+			if c != nil {
+				c.setErr(err)
+			} else if err != nil {
+				defaultErrorHandler(w, r, err)
+			}
+		}))
+
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			c := &errorCarrier{ResponseWriter: w}
+			wrapped.ServeHTTP(c, r.WithContext(context.WithValue(r.Context(), errorCarrierKey, c)))
+			return c.result()
+		})
+	}
+}
+
+// ToHTTPMiddleware adapts the error-aware mw to a [httputils.Middleware] which
+// can be used with plain [http.Handler]s. The response is buffered; any error
+// returned from mw causes the response to be discarded and the error to be
+// handled by errorHandler. If errorHandler is nil, the default error handler is
+// used.
+func ToHTTPMiddleware(mw Middleware, errorHandler ErrorHandler) httputils.Middleware {
+	if errorHandler == nil {
+		errorHandler = defaultErrorHandler
+	}
+
+	return func(next http.Handler) http.Handler {
+		h := mw(HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			next.ServeHTTP(w, r)
+			return nil
+		}))
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var buf bufferedresponse.ResponseWriter
+			if err := h.ServeHTTP(&buf, r); err != nil {
+				errorHandler(w, r, err)
+				return
+			}
+			buf.WriteTo(w)
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package errmux
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/halimath/expect"
+	"github.com/halimath/expect/is"
+	"github.com/halimath/httputils/requestbuilder"
+)
+
+func recordingMiddleware(name string, order *[]string) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			*order = append(*order, name)
+			return next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func TestServeMux_Use(t *testing.T) {
+	var order []string
+
+	mux := NewServeMux()
+	noop := func(http.ResponseWriter, *http.Request) error { return nil }
+
+	mux.HandleFunc("/before", noop)
+	mux.Use(recordingMiddleware("inner", &order), recordingMiddleware("outer", &order))
+	mux.HandleFunc("/after", noop)
+
+	mux.ServeHTTP(httptest.NewRecorder(), requestbuilder.Get("/before").Request())
+	expect.That(t, is.SliceOfLen(order, 0))
+
+	mux.ServeHTTP(httptest.NewRecorder(), requestbuilder.Get("/after").Request())
+	expect.That(t, is.DeepEqualTo(order, []string{"outer", "inner"}))
+}
+
+func TestServeMux_Group(t *testing.T) {
+	var order []string
+
+	mux := NewServeMux()
+	mux.Use(recordingMiddleware("mux", &order))
+
+	api := mux.Group("/api", recordingMiddleware("api", &order))
+	api.HandleFunc("GET /orders/{id}", func(w http.ResponseWriter, r *http.Request) error {
+		order = append(order, "handler "+r.PathValue("id"))
+		return nil
+	})
+
+	admin := api.Group("/admin/", recordingMiddleware("admin", &order))
+	admin.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) error {
+		order = append(order, "admin handler")
+		return nil
+	})
+
+	mux.ServeHTTP(httptest.NewRecorder(), requestbuilder.Get("/api/orders/17").Request())
+	expect.That(t,
+		is.DeepEqualTo(order, []string{"mux", "api", "handler 17"}),
+		is.DeepEqualTo(mux.Patterns(), []string{"GET /api/orders/{id}", "/api/admin/"}),
+	)
+
+	order = nil
+	mux.ServeHTTP(httptest.NewRecorder(), requestbuilder.Get("/api/admin/users").Request())
+	expect.That(t, is.DeepEqualTo(order, []string{"mux", "api", "admin", "admin handler"}))
+}
+
+func TestFromHTTPMiddleware(t *testing.T) {
+	errKaboom := errors.New("kaboom")
+
+	var headerSet bool
+	httpMiddleware := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Middleware", "true")
+			next.ServeHTTP(w, r)
+			headerSet = true
+		})
+	}
+
+	mux := NewServeMux()
+	var handledError error
+	mux.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		handledError = err
+		w.WriteHeader(http.StatusTeapot)
+	}
+
+	mux.Use(FromHTTPMiddleware(httpMiddleware))
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) error {
+		return errKaboom
+	})
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, requestbuilder.Get("/").Request())
+
+	expect.That(t,
+		is.EqualTo(headerSet, true),
+		is.Error(handledError, errKaboom),
+		is.EqualTo(w.Result().StatusCode, http.StatusTeapot),
+	)
+}
+
+func TestFromHTTPMiddleware_detachedContext(t *testing.T) {
+	errKaboom := errors.New("kaboom")
+
+	httpMiddleware := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+
+	h := FromHTTPMiddleware(httpMiddleware)(HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		return errKaboom
+	}))
+
+	err := h.ServeHTTP(httptest.NewRecorder(), requestbuilder.Get("/").Request())
+	expect.That(t, is.Error(err, errKaboom))
+}
+
+func TestFromHTTPMiddleware_appliedOnce(t *testing.T) {
+	var calls int
+	httpMiddleware := func(next http.Handler) http.Handler {
+		calls++
+		return next
+	}
+
+	mux := NewServeMux()
+	mux.Use(FromHTTPMiddleware(httpMiddleware))
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) error {
+		return nil
+	})
+
+	for range 3 {
+		mux.ServeHTTP(httptest.NewRecorder(), requestbuilder.Get("/").Request())
+	}
+
+	expect.That(t, is.EqualTo(calls, 1))
+}
+
+func TestFromHTTPMiddleware_replacedWriter(t *testing.T) {
+	errKaboom := errors.New("kaboom")
+
+	httpMiddleware := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(httptest.NewRecorder(), r)
+		})
+	}
+
+	h := FromHTTPMiddleware(httpMiddleware)(HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		return errKaboom
+	}))
+
+	err := h.ServeHTTP(httptest.NewRecorder(), requestbuilder.Get("/").Request())
+	expect.That(t, is.Error(err, errKaboom))
+}
+
+func TestToHTTPMiddleware(t *testing.T) {
+	errForbidden := errors.New("forbidden")
+
+	mw := func(next Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			if r.URL.Query().Get("allow") != "true" {
+				return errForbidden
+			}
+			return next.ServeHTTP(w, r)
+		})
+	}
+
+	h := ToHTTPMiddleware(mw, func(w http.ResponseWriter, r *http.Request, err error) {
+		w.WriteHeader(http.StatusForbidden)
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, requestbuilder.Get("/").Request())
+	expect.That(t, is.EqualTo(w.Result().StatusCode, http.StatusForbidden))
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, requestbuilder.Get("/?allow=true").Request())
+	expect.That(t, is.EqualTo(w.Result().StatusCode, http.StatusNoContent))
+}
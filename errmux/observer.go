@@ -0,0 +1,141 @@
+package errmux
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/halimath/httputils/recovery"
+	"github.com/halimath/httputils/requestid"
+	"github.com/halimath/httputils/response"
+	"github.com/halimath/kvlog"
+)
+
+// ErrorEvent describes an error (or a recovered panic) handled by a
+// [ServeMux].
+type ErrorEvent struct {
+	// Request is the request that caused the error.
+	Request *http.Request
+
+	// Pattern is the pattern of the route that handled the request. It is
+	// empty for requests that matched no route.
+	Pattern string
+
+	// Status is the HTTP status code sent.
+	Status int
+
+	// Err is the error being handled. Panics are reported as a
+	// [*recovery.PanicError].
+	Err error
+
+	// Stack contains the program counters of the stack trace captured by the
+	// error (see [response.StackTracer]) or nil if the error captured none.
+	Stack []uintptr
+
+	// RequestID contains the ID assigned to the request by
+	// [requestid.NewMiddleware] - if any.
+	RequestID string
+
+	// ResponseSent is true if the error occurred after a streamed response
+	// has been sent (see [Streaming]). In this case Status contains the
+	// status code already sent.
+	ResponseSent bool
+}
+
+// Panic reports whether e has been caused by a panic.
+func (e ErrorEvent) Panic() bool {
+	var pe *recovery.PanicError
+	return errors.As(e.Err, &pe)
+}
+
+// ErrorObserver defines the interface for types that get notified about every
+// error handled by a [ServeMux], i.e. to report them to an error tracking
+// service. ObserveError is invoked after the error response has been sent.
+type ErrorObserver interface {
+	ObserveError(e ErrorEvent)
+}
+
+// ErrorObserverFunc is a convenience function type implementing
+// [ErrorObserver].
+type ErrorObserverFunc func(e ErrorEvent)
+
+func (f ErrorObserverFunc) ObserveError(e ErrorEvent) { f(e) }
+
+// LogErrorObserver is the default [ErrorObserver] of a [ServeMux]. It logs
+// server errors (status >= 500), panics and errors occurring after a streamed
+// response has been sent using the [kvlog.Logger] found in the request's
+// context. Client errors are not logged.
+var LogErrorObserver ErrorObserver = ErrorObserverFunc(logError)
+
+func logError(e ErrorEvent) {
+	if e.Status < http.StatusInternalServerError && !e.ResponseSent && !e.Panic() {
+		return
+	}
+
+	pairs := []*kvlog.Pair{
+		kvlog.WithKV("method", e.Request.Method),
+		kvlog.WithKV("path", e.Request.URL.Path),
+		kvlog.WithKV("pattern", e.Pattern),
+		kvlog.WithKV("status", e.Status),
+	}
+
+	if e.Stack != nil {
+		pairs = append(pairs, kvlog.WithKV("stack", response.FormatStackTrace(e.Stack)))
+	}
+
+	pairs = append(pairs, kvlog.WithErr(e.Err))
+
+	msg := "error handling request"
+	if e.ResponseSent {
+		msg = "error after response has been sent"
+	}
+
+	kvlog.FromContext(e.Request.Context()).Logs(msg, pairs...)
+}
+
+// observeError notifies mux's ErrorObserver about err.
+func (mux *ServeMux) observeError(r *http.Request, pattern string, status int, err error, responseSent bool) {
+	if mux.ErrorObserver == nil {
+		return
+	}
+
+	e := ErrorEvent{
+		Request:      r,
+		Pattern:      pattern,
+		Status:       status,
+		Err:          err,
+		RequestID:    requestid.FromContext(r.Context()),
+		ResponseSent: responseSent,
+	}
+
+	var st response.StackTracer
+	if errors.As(err, &st) {
+		e.Stack = st.StackTrace()
+	}
+
+	mux.ErrorObserver.ObserveError(e)
+}
+
+// statusRecorder wraps a [http.ResponseWriter] and records the status code
+// sent.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusRecorder) WriteHeader(statusCode int) {
+	if w.status == 0 {
+		w.status = statusCode
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *statusRecorder) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// Unwrap returns the wrapped [http.ResponseWriter] to support
+// [http.ResponseController].
+func (w *statusRecorder) Unwrap() http.ResponseWriter { return w.ResponseWriter }
@@ -0,0 +1,83 @@
+package errmux
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/halimath/expect"
+	"github.com/halimath/expect/is"
+	"github.com/halimath/httputils/requestbuilder"
+	"github.com/halimath/httputils/requestid"
+	"github.com/halimath/httputils/response"
+)
+
+func TestServeMux_ErrorObserver(t *testing.T) {
+	var events []ErrorEvent
+
+	mux := NewServeMux()
+	mux.ErrorObserver = ErrorObserverFunc(func(e ErrorEvent) {
+		events = append(events, e)
+	})
+
+	mux.HandleFunc("GET /panic", func(w http.ResponseWriter, r *http.Request) error {
+		panic("kaboom")
+	})
+
+	mux.HandleFunc("GET /stream", func(w http.ResponseWriter, r *http.Request) error {
+		io.WriteString(w, "data")
+		http.NewResponseController(w).Flush()
+		return errors.New("kaboom")
+	}, Streaming(0), ErrorTrailer("X-Error"))
+
+	handler := requestid.NewMiddleware(requestid.WithGenerator(func() string { return "req-1" }))(mux)
+
+	t.Run("panic", func(t *testing.T) {
+		events = nil
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, requestbuilder.Get("/panic").Request())
+
+		var pd response.ProblemDetails
+		err := json.Unmarshal(w.Body.Bytes(), &pd)
+
+		expect.That(t,
+			is.NoError(err),
+			is.EqualTo(pd.Instance, "req-1"),
+			expect.FailNow(is.SliceOfLen(events, 1)),
+			is.EqualTo(events[0].Pattern, "GET /panic"),
+			is.EqualTo(events[0].Status, http.StatusInternalServerError),
+			is.EqualTo(events[0].RequestID, "req-1"),
+			is.EqualTo(events[0].Panic(), true),
+			is.EqualTo(len(events[0].Stack) > 0, true),
+			is.EqualTo(events[0].ResponseSent, false),
+		)
+	})
+
+	t.Run("notFound", func(t *testing.T) {
+		events = nil
+		handler.ServeHTTP(httptest.NewRecorder(), requestbuilder.Get("/unknown").Request())
+
+		expect.That(t,
+			expect.FailNow(is.SliceOfLen(events, 1)),
+			is.EqualTo(events[0].Pattern, ""),
+			is.EqualTo(events[0].Status, http.StatusNotFound),
+			is.Error(events[0].Err, ErrNotFound),
+			is.EqualTo(events[0].Panic(), false),
+		)
+	})
+
+	t.Run("afterResponseSent", func(t *testing.T) {
+		events = nil
+		handler.ServeHTTP(httptest.NewRecorder(), requestbuilder.Get("/stream").Request())
+
+		expect.That(t,
+			expect.FailNow(is.SliceOfLen(events, 1)),
+			is.EqualTo(events[0].Pattern, "GET /stream"),
+			is.EqualTo(events[0].Status, http.StatusOK),
+			is.EqualTo(events[0].ResponseSent, true),
+		)
+	})
+}
@@ -0,0 +1,131 @@
+package errmux
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/halimath/httputils"
+	"github.com/halimath/httputils/internal/accept"
+	"github.com/halimath/httputils/response"
+)
+
+const (
+	contentTypeProblemJSON = "application/problem+json"
+	contentTypeProblemXML  = "application/problem+xml"
+	contentTypeJSON        = "application/json"
+	contentTypeXML         = "application/xml"
+	contentTypeHTML        = "text/html"
+	contentTypePlainText   = "text/plain"
+)
+
+// errorContentTypes lists the content types offered when rendering errors in
+// order of preference. application/json and application/xml are rendered as
+// their problem details counterparts.
+var errorContentTypes = []string{
+	contentTypeProblemJSON,
+	contentTypeProblemXML,
+	contentTypeHTML,
+	contentTypePlainText,
+	contentTypeJSON,
+	contentTypeXML,
+}
+
+// DefaultErrorPageTemplate is the template used to render HTML error pages if
+// an [ErrorRegistry] has no ErrorPageTemplate. Templates are executed with the
+// [response.ProblemDetails] to render.
+var DefaultErrorPageTemplate = template.Must(template.New("error").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{ .Status }} {{ .Title }}</title>
+</head>
+<body>
+<h1>{{ .Status }} {{ .Title }}</h1>
+{{ if .Detail }}<pre>{{ .Detail }}</pre>
+{{ end }}</body>
+</html>
+`))
+
+// render sends pd using the content type negotiated from r's Accept header.
+// If no content type is acceptable, problem details are sent as JSON anyway
+// as an error response is better than none.
+func (reg *ErrorRegistry) render(w http.ResponseWriter, r *http.Request, pd response.ProblemDetails) {
+	httputils.AddVary(w.Header(), "Accept")
+
+	contentType, ok := accept.Negotiate(strings.Join(r.Header.Values("Accept"), ","), errorContentTypes...)
+	if !ok {
+		contentType = contentTypeProblemJSON
+	}
+
+	switch contentType {
+	case contentTypeProblemXML, contentTypeXML:
+		response.ProblemXML(w, r, pd)
+
+	case contentTypeHTML:
+		pd, resolved := response.DefaultProblemTypeRegistry.Resolve(r, pd)
+		reg.renderHTML(w, r, pd, resolved)
+
+	case contentTypePlainText:
+		pd, resolved := response.DefaultProblemTypeRegistry.Resolve(r, pd)
+		renderPlainText(w, r, pd, resolved)
+
+	default:
+		response.Problem(w, r, pd)
+	}
+}
+
+// renderHTML renders pd using reg's ErrorPageTemplate. If the template fails
+// to execute, pd is rendered as plain text. opts are applied to the response.
+func (reg *ErrorRegistry) renderHTML(w http.ResponseWriter, r *http.Request, pd response.ProblemDetails, opts ...response.Option) {
+	tpl := reg.ErrorPageTemplate
+	if tpl == nil {
+		tpl = DefaultErrorPageTemplate
+	}
+
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, pd); err != nil {
+		renderPlainText(w, r, pd, opts...)
+		return
+	}
+
+	response.Send(w, r, append(opts,
+		response.SetHeader("Content-Type", "text/html; charset=utf-8", true),
+		response.SetHeader("Content-Length", strconv.Itoa(buf.Len()), true),
+		response.StatusCode(pd.Status),
+		response.WriteBody(buf.Bytes()),
+	)...)
+}
+
+// renderPlainText renders pd as plain text. opts are applied to the response.
+func renderPlainText(w http.ResponseWriter, r *http.Request, pd response.ProblemDetails, opts ...response.Option) {
+	body := fmt.Sprintf("%d %s\n", pd.Status, pd.Title)
+	if pd.Detail != "" {
+		body += "\n" + pd.Detail + "\n"
+	}
+
+	response.PlainText(w, r, body, append(opts, response.StatusCode(pd.Status))...)
+}
+
+// unmappedProblemDetails creates problem details for an error not mapped by an
+// [ErrorRegistry]. If r's [response.Config] enables ErrorDetails (i.e. if
+// [response.DevMode] is enabled), details contain the error's message, type
+// and stack trace (if err provides one).
+func unmappedProblemDetails(r *http.Request, err error) response.ProblemDetails {
+	pd := completeProblemDetails(response.ProblemDetails{Status: http.StatusInternalServerError})
+
+	if response.ConfigFromRequest(r).ErrorDetails {
+		pd.Detail = fmt.Sprintf("%s (%T)", err.Error(), err)
+
+		var st response.StackTracer
+		if errors.As(err, &st) {
+			pd.Detail += "\n" + response.FormatStackTrace(st.StackTrace())
+		}
+	}
+
+	return pd
+}
@@ -0,0 +1,246 @@
+package errmux
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/halimath/httputils/auth"
+)
+
+// route holds the configuration of a single handler registered with a
+// [ServeMux].
+type route struct {
+	pattern string
+
+	// streaming enables bounded buffering using threshold as the maximum
+	// number of bytes to buffer.
+	streaming bool
+	threshold int
+
+	// errorTrailer defines the name of a trailer used to report errors after
+	// the response has been spilled.
+	errorTrailer string
+
+	// info holds the route's metadata used for introspection.
+	info RouteInfo
+
+	// describedResponses is set when info.Responses has been populated by a
+	// routeDescriber; documented responses replace these.
+	describedResponses bool
+}
+
+// RouteOption defines a function type to customize a single route registered
+// with a [ServeMux].
+type RouteOption func(*route)
+
+// Streaming is a [RouteOption] that replaces the full buffering of the
+// response with a bounded buffer of threshold bytes. Once the response body
+// exceeds threshold or the handler flushes the response (i.e. using
+// [http.ResponseController] or [http.Flusher]), the buffered response is
+// sent and all further data is streamed directly to the client. Use a
+// threshold of 0 to stream right from the first byte written.
+//
+// Errors returned (or panics raised) by the handler before the response has
+// been sent are handled as usual. Errors returned after the response has been
+// sent can no longer replace the response; they are reported to the
+// ServeMux's ErrorObserver (with ResponseSent set). Afterwards the response is
+// aborted by panicking with [http.ErrAbortHandler], so the client sees a
+// broken connection instead of a truncated but seemingly complete response.
+// Errors (but not panics) are instead reported as a trailer if [ErrorTrailer]
+// is used.
+func Streaming(threshold int) RouteOption {
+	return func(rt *route) {
+		rt.streaming = true
+		rt.threshold = threshold
+	}
+}
+
+// ErrorTrailer is a [RouteOption] that reports errors occuring after a
+// streamed response has been sent as a HTTP trailer named name. The trailer's
+// value contains the error's message. This option only has an effect when used
+// in combination with [Streaming].
+func ErrorTrailer(name string) RouteOption {
+	return func(rt *route) {
+		rt.errorTrailer = name
+	}
+}
+
+// --
+
+// RouteInfo describes a route registered with a [ServeMux]. It is used to
+// introspect the routes of a ServeMux (see [ServeMux.Routes]), i.e. to
+// generate API documentation.
+type RouteInfo struct {
+	// Pattern is the pattern the route has been registered with.
+	Pattern string
+
+	// Method, Host and Path contain the parts of Pattern. Method and Host are
+	// empty if Pattern contains no method or host.
+	Method string
+	Host   string
+	Path   string
+
+	// OperationID uniquely identifies the route - optional.
+	OperationID string
+
+	// Summary contains a short summary of what the route does - optional.
+	Summary string
+
+	// Description contains a verbose description - optional.
+	Description string
+
+	// Tags group routes - optional.
+	Tags []string
+
+	// Deprecated marks the route as deprecated.
+	Deprecated bool
+
+	// Hidden excludes the route from generated documentation.
+	Hidden bool
+
+	// Request is the type of the request - optional. See [RequestBody].
+	Request reflect.Type
+
+	// Responses lists the documented responses - optional.
+	Responses []ResponseInfo
+
+	// Security lists the authentication challenges accepted by the route. A
+	// client must satisfy one of them - optional.
+	Security []auth.AuthenticationChallenge
+}
+
+// ResponseInfo describes a single response of a route.
+type ResponseInfo struct {
+	// Status is the HTTP status code.
+	Status int
+
+	// Description contains a human readable description.
+	Description string
+
+	// Type is the type of the response body. Nil if the response has no body.
+	Type reflect.Type
+}
+
+// resetDescribedResponses removes responses populated by a routeDescriber.
+func (rt *route) resetDescribedResponses() {
+	if rt.describedResponses {
+		rt.info.Responses = nil
+		rt.describedResponses = false
+	}
+}
+
+// routeDescriber is implemented by handlers that can describe the route they
+// are registered with.
+type routeDescriber interface {
+	describeRoute(info *RouteInfo)
+}
+
+// parsePattern splits pattern into method, host and path.
+func parsePattern(pattern string) (method, host, path string) {
+	i := strings.IndexByte(pattern, '/')
+	if i < 0 {
+		return "", "", pattern
+	}
+
+	path = pattern[i:]
+
+	fields := strings.Fields(pattern[:i])
+	switch len(fields) {
+	case 0:
+		return "", "", path
+	case 1:
+		if strings.HasSuffix(pattern[:i], fields[0]) {
+			// No whitespace between the prefix and the path: host only.
+			return "", fields[0], path
+		}
+		return fields[0], "", path
+	default:
+		return fields[0], fields[1], path
+	}
+}
+
+// OperationID is a [RouteOption] that sets the route's operation id.
+func OperationID(id string) RouteOption {
+	return func(rt *route) {
+		rt.info.OperationID = id
+	}
+}
+
+// Summary is a [RouteOption] that sets the route's summary.
+func Summary(summary string) RouteOption {
+	return func(rt *route) {
+		rt.info.Summary = summary
+	}
+}
+
+// Description is a [RouteOption] that sets the route's description.
+func Description(description string) RouteOption {
+	return func(rt *route) {
+		rt.info.Description = description
+	}
+}
+
+// Tags is a [RouteOption] that adds tags to the route.
+func Tags(tags ...string) RouteOption {
+	return func(rt *route) {
+		rt.info.Tags = append(rt.info.Tags, tags...)
+	}
+}
+
+// Deprecated is a [RouteOption] that marks the route as deprecated.
+func Deprecated() RouteOption {
+	return func(rt *route) {
+		rt.info.Deprecated = true
+	}
+}
+
+// Hidden is a [RouteOption] that excludes the route from generated
+// documentation.
+func Hidden() RouteOption {
+	return func(rt *route) {
+		rt.info.Hidden = true
+	}
+}
+
+// RequestBody is a [RouteOption] that documents T as the route's request
+// type. Routes using a handler created with [Typed] document their request
+// type automatically.
+func RequestBody[T any]() RouteOption {
+	return func(rt *route) {
+		rt.info.Request = reflect.TypeFor[T]()
+	}
+}
+
+// Response is a [RouteOption] that documents a response with status carrying
+// a body of type T. Routes using a handler created with [Typed] document
+// their response type automatically unless a Response option is given.
+func Response[T any](status int, description string) RouteOption {
+	return func(rt *route) {
+		rt.resetDescribedResponses()
+		rt.info.Responses = append(rt.info.Responses, ResponseInfo{
+			Status:      status,
+			Description: description,
+			Type:        reflect.TypeFor[T](),
+		})
+	}
+}
+
+// EmptyResponse is a [RouteOption] that documents a response with status that
+// carries no body.
+func EmptyResponse(status int, description string) RouteOption {
+	return func(rt *route) {
+		rt.resetDescribedResponses()
+		rt.info.Responses = append(rt.info.Responses, ResponseInfo{
+			Status:      status,
+			Description: description,
+		})
+	}
+}
+
+// Security is a [RouteOption] that documents the authentication challenges
+// accepted by the route, i.e. the ones passed to [auth.Authorized].
+func Security(challenges ...auth.AuthenticationChallenge) RouteOption {
+	return func(rt *route) {
+		rt.info.Security = append(rt.info.Security, challenges...)
+	}
+}
@@ -0,0 +1,383 @@
+package errmux
+
+import (
+	"context"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/halimath/httputils/internal/accept"
+	"github.com/halimath/httputils/response"
+	"github.com/halimath/httputils/validate"
+)
+
+// TypedHandlerFunc defines the function signature for typed handlers used with
+// [Typed].
+type TypedHandlerFunc[Req, Resp any] func(ctx context.Context, req Req) (Resp, error)
+
+// Validator may be implemented by request types used with [Typed] to validate
+// a decoded request. A non-nil error returned from Validate is returned from
+// the handler.
+type Validator interface {
+	Validate() error
+}
+
+// StatusCoder may be implemented by response types used with [Typed] to
+// define the HTTP status code to send. By default, [http.StatusOK] is used.
+type StatusCoder interface {
+	StatusCode() int
+}
+
+// Typed adapts the typed handler f to a [Handler]. The returned Handler
+// decodes the request into a value of type Req, invokes f and encodes the
+// returned value of type Resp as the response.
+//
+// Decoding works as follows:
+//
+//   - If the request carries a JSON body (content type application/json or
+//     any content type with a +json suffix), the body is decoded into Req
+//     using [json.Decoder].
+//   - If the request carries a form body, form values are assigned to the
+//     fields tagged with form:"name".
+//   - Path values are assigned to fields tagged with path:"name" (see
+//     [http.Request.PathValue]), query parameters to fields tagged with
+//     query:"name" and request headers to fields tagged with header:"name".
+//
+// Tagged fields may be of type string, bool, any integer or floating point
+// type, [time.Duration], [time.Time] (using RFC 3339), any type implementing
+// [encoding.TextUnmarshaler], a pointer to one of these types or a slice of
+// these types (to receive all values). Any decoding failure causes a
+// [*DecodeError] to be returned, which is mapped to a 400 response with
+// problem details by the [DefaultErrorRegistry].
+//
+// After decoding, struct requests are validated using [validate.Struct]. If
+// Req implements [Validator], Validate is invoked afterwards.
+//
+// The response is sent using [response.Negotiate] with status code 200 unless
+// Resp implements [StatusCoder]. The Accept header is checked before the
+// request is decoded: if none of the media types registered with
+// [response.DefaultEncoderRegistry] is acceptable, a 406 response is sent
+// without invoking f.
+func Typed[Req, Resp any](f TypedHandlerFunc[Req, Resp]) Handler {
+	return typedHandler[Req, Resp](f)
+}
+
+// typedHandler implements the Handler returned from Typed.
+type typedHandler[Req, Resp any] TypedHandlerFunc[Req, Resp]
+
+func (f typedHandler[Req, Resp]) ServeHTTP(w http.ResponseWriter, r *http.Request) error {
+	if !acceptable(r) {
+		return NewStatusError(http.StatusNotAcceptable, nil)
+	}
+
+	req, err := DecodeRequest[Req](r)
+	if err != nil {
+		return err
+	}
+
+	if reflect.Indirect(reflect.ValueOf(req)).Kind() == reflect.Struct {
+		if err := validate.Struct(req); err != nil {
+			return err
+		}
+	}
+
+	if v, ok := any(&req).(Validator); ok {
+		if err := v.Validate(); err != nil {
+			return err
+		}
+	}
+
+	resp, err := f(r.Context(), req)
+	if err != nil {
+		return err
+	}
+
+	status := http.StatusOK
+	if v := reflect.ValueOf(resp); !v.IsValid() || v.Kind() != reflect.Pointer || !v.IsNil() {
+		// A nil pointer would panic when StatusCode uses a value receiver.
+		if sc, ok := any(resp).(StatusCoder); ok {
+			status = sc.StatusCode()
+		}
+	}
+
+	return response.Negotiate(w, r, resp, response.StatusCode(status))
+}
+
+// describeRoute documents Req as the route's request type and Resp as the
+// route's response type.
+func (f typedHandler[Req, Resp]) describeRoute(info *RouteInfo) {
+	info.Request = reflect.TypeFor[Req]()
+
+	status := http.StatusOK
+	if reflect.TypeFor[Resp]().Kind() != reflect.Pointer {
+		var zero Resp
+		if sc, ok := any(zero).(StatusCoder); ok {
+			status = sc.StatusCode()
+		}
+	}
+
+	info.Responses = []ResponseInfo{{
+		Status:      status,
+		Description: http.StatusText(status),
+		Type:        reflect.TypeFor[Resp](),
+	}}
+}
+
+// acceptable reports whether r's Accept header allows any of the media types
+// registered with response.DefaultEncoderRegistry.
+func acceptable(r *http.Request) bool {
+	_, ok := accept.Negotiate(strings.Join(r.Header.Values("Accept"), ","), response.DefaultEncoderRegistry.MediaTypes()...)
+	return ok
+}
+
+// --
+
+// DecodeError is returned from [DecodeRequest] when a request cannot be
+// decoded.
+type DecodeError struct {
+	// Source names the request part that failed to decode: one of path,
+	// query, header, form or body.
+	Source string
+
+	// Name of the parameter that failed to decode. Empty for body errors.
+	Name string
+
+	// The underlying error.
+	Err error
+}
+
+func (e *DecodeError) Error() string {
+	if e.Name == "" {
+		return fmt.Sprintf("invalid %s: %v", e.Source, e.Err)
+	}
+	return fmt.Sprintf("invalid %s parameter %q: %v", e.Source, e.Name, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error { return e.Err }
+
+// DecodeRequest decodes r into a value of type Req. See [Typed] for a
+// description of the decoding rules.
+func DecodeRequest[Req any](r *http.Request) (Req, error) {
+	var req Req
+
+	if err := decodeBody(r, &req); err != nil {
+		return req, err
+	}
+
+	v := reflect.ValueOf(&req).Elem()
+	if v.Kind() != reflect.Struct {
+		return req, nil
+	}
+
+	if err := decodeFields(r, v); err != nil {
+		return req, err
+	}
+
+	return req, nil
+}
+
+// decodeBody decodes r's body into target based on the request's content type.
+func decodeBody(r *http.Request, target any) error {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil
+	}
+
+	contentType := r.Header.Get("Content-Type")
+	if contentType == "" {
+		return nil
+	}
+
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return &DecodeError{Source: "body", Err: err}
+	}
+
+	switch {
+	case mt == "application/json" || strings.HasSuffix(mt, "+json"):
+		if err := json.NewDecoder(r.Body).Decode(target); err != nil && !errors.Is(err, io.EOF) {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				return err
+			}
+			return &DecodeError{Source: "body", Err: err}
+		}
+		return nil
+
+	case mt == "application/x-www-form-urlencoded":
+		if err := r.ParseForm(); err != nil {
+			return &DecodeError{Source: "form", Err: err}
+		}
+		return nil
+
+	case mt == "multipart/form-data":
+		if err := r.ParseMultipartForm(32 << 20); err != nil {
+			return &DecodeError{Source: "form", Err: err}
+		}
+		return nil
+
+	default:
+		return NewStatusError(http.StatusUnsupportedMediaType, fmt.Errorf("unsupported content type: %s", mt))
+	}
+}
+
+// decodeFields assigns path values, query parameters, headers and form values
+// to the tagged fields of the struct v.
+func decodeFields(r *http.Request, v reflect.Value) error {
+	t := v.Type()
+
+	var query map[string][]string
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		fv := v.Field(i)
+
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			if err := decodeFields(r, fv); err != nil {
+				return err
+			}
+			continue
+		}
+
+		var source, name string
+		var values []string
+
+		if name = f.Tag.Get("path"); name != "" {
+			source = "path"
+			if val := r.PathValue(name); val != "" {
+				values = []string{val}
+			}
+		} else if name = f.Tag.Get("query"); name != "" {
+			source = "query"
+			if query == nil {
+				query = r.URL.Query()
+			}
+			values = query[name]
+		} else if name = f.Tag.Get("header"); name != "" {
+			source = "header"
+			values = r.Header.Values(name)
+		} else if name = f.Tag.Get("form"); name != "" {
+			source = "form"
+			if r.PostForm != nil {
+				values = r.PostForm[name]
+			}
+		} else {
+			continue
+		}
+
+		if len(values) == 0 {
+			continue
+		}
+
+		if err := setField(fv, values); err != nil {
+			return &DecodeError{Source: source, Name: name, Err: err}
+		}
+	}
+
+	return nil
+}
+
+var (
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+	durationType        = reflect.TypeFor[time.Duration]()
+	timeType            = reflect.TypeFor[time.Time]()
+)
+
+// setField assigns values to fv converting them to fv's type.
+func setField(fv reflect.Value, values []string) error {
+	if fv.Kind() == reflect.Slice && !fv.Type().Implements(textUnmarshalerType) && fv.Type().Elem().Kind() != reflect.Uint8 {
+		s := reflect.MakeSlice(fv.Type(), len(values), len(values))
+		for i, val := range values {
+			if err := setValue(s.Index(i), val); err != nil {
+				return err
+			}
+		}
+		fv.Set(s)
+		return nil
+	}
+
+	return setValue(fv, values[0])
+}
+
+// setValue assigns the single value val to fv converting it to fv's type.
+func setValue(fv reflect.Value, val string) error {
+	if fv.Kind() == reflect.Pointer {
+		p := reflect.New(fv.Type().Elem())
+		if err := setValue(p.Elem(), val); err != nil {
+			return err
+		}
+		fv.Set(p)
+		return nil
+	}
+
+	if fv.CanAddr() && fv.Addr().Type().Implements(textUnmarshalerType) {
+		return fv.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(val))
+	}
+
+	switch fv.Type() {
+	case durationType:
+		d, err := time.ParseDuration(val)
+		if err != nil {
+			return err
+		}
+		fv.SetInt(int64(d))
+		return nil
+
+	case timeType:
+		t, err := time.Parse(time.RFC3339, val)
+		if err != nil {
+			return err
+		}
+		fv.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(val)
+
+	case reflect.Bool:
+		b, err := strconv.ParseBool(val)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(val, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetInt(i)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(val, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetUint(u)
+
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(val, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(f)
+
+	default:
+		return fmt.Errorf("unsupported field type: %s", fv.Type())
+	}
+
+	return nil
+}
@@ -0,0 +1,195 @@
+package errmux
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/halimath/expect"
+	"github.com/halimath/expect/is"
+	"github.com/halimath/httputils/requestbuilder"
+)
+
+type getOrderRequest struct {
+	ID      int           `path:"id" json:"-"`
+	Fields  []string      `query:"field" json:"-"`
+	Timeout time.Duration `query:"timeout" json:"-"`
+	Limit   *uint         `query:"limit" json:"-"`
+	Tenant  string        `header:"X-Tenant" json:"-"`
+	Note    string        `json:"note"`
+}
+
+func (r *getOrderRequest) Validate() error {
+	if r.Tenant == "" {
+		return NewStatusError(http.StatusUnprocessableEntity, errors.New("missing tenant"))
+	}
+	return nil
+}
+
+type created struct {
+	Result string `json:"result"`
+}
+
+func (created) StatusCode() int { return http.StatusCreated }
+
+func TestTyped(t *testing.T) {
+	var got getOrderRequest
+
+	mux := NewServeMux()
+	mux.Handle("POST /orders/{id}", Typed(func(ctx context.Context, req getOrderRequest) (created, error) {
+		got = req
+		return created{Result: "ok"}, nil
+	}))
+
+	t.Run("success", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, requestbuilder.Post("/orders/17?field=a&field=b&timeout=2s&limit=5").
+			AddHeader("X-Tenant", "acme").
+			AddHeader("Content-Type", "application/json").
+			Body(strings.NewReader(`{"note":"hello"}`)).
+			Request())
+
+		limit := uint(5)
+		expect.That(t,
+			is.EqualTo(w.Code, http.StatusCreated),
+			is.EqualTo(w.Header().Get("Content-Type"), "application/json"),
+			is.EqualTo(strings.TrimSpace(w.Body.String()), `{"result":"ok"}`),
+			is.DeepEqualTo(got, getOrderRequest{
+				ID:      17,
+				Fields:  []string{"a", "b"},
+				Timeout: 2 * time.Second,
+				Limit:   &limit,
+				Tenant:  "acme",
+				Note:    "hello",
+			}),
+		)
+	})
+
+	t.Run("invalid_path_value", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, requestbuilder.Post("/orders/abc").AddHeader("X-Tenant", "acme").Request())
+
+		expect.That(t,
+			is.EqualTo(w.Code, http.StatusBadRequest),
+			is.EqualTo(w.Header().Get("Content-Type"), "application/problem+json"),
+			is.StringContaining(w.Body.String(), `invalid path parameter \"id\"`),
+		)
+	})
+
+	t.Run("invalid_body", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, requestbuilder.Post("/orders/17").
+			AddHeader("X-Tenant", "acme").
+			AddHeader("Content-Type", "application/json").
+			Body(strings.NewReader(`{"note":`)).
+			Request())
+
+		expect.That(t, is.EqualTo(w.Code, http.StatusBadRequest))
+	})
+
+	t.Run("unsupported_content_type", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, requestbuilder.Post("/orders/17").
+			AddHeader("X-Tenant", "acme").
+			AddHeader("Content-Type", "text/csv").
+			Body(strings.NewReader(`a,b`)).
+			Request())
+
+		expect.That(t, is.EqualTo(w.Code, http.StatusUnsupportedMediaType))
+	})
+
+	t.Run("validation_failed", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, requestbuilder.Post("/orders/17").Request())
+
+		expect.That(t, is.EqualTo(w.Code, http.StatusUnprocessableEntity))
+	})
+
+	t.Run("negotiated", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, requestbuilder.Post("/orders/17").
+			AddHeader("X-Tenant", "acme").
+			AddHeader("Accept", "application/xml").
+			Request())
+
+		expect.That(t,
+			is.EqualTo(w.Code, http.StatusCreated),
+			is.EqualTo(w.Header().Get("Content-Type"), "application/xml"),
+		)
+	})
+
+	t.Run("not_acceptable", func(t *testing.T) {
+		got = getOrderRequest{}
+
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, requestbuilder.Post("/orders/17").
+			AddHeader("X-Tenant", "acme").
+			AddHeader("Accept", "text/html").
+			Request())
+
+		expect.That(t,
+			is.EqualTo(w.Code, http.StatusNotAcceptable),
+			is.DeepEqualTo(got, getOrderRequest{}),
+		)
+	})
+}
+
+func TestTyped_nilPointerResponse(t *testing.T) {
+	mux := NewServeMux()
+	mux.Handle("GET /orders", Typed(func(ctx context.Context, req struct{}) (*created, error) {
+		return nil, nil
+	}))
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, requestbuilder.Get("/orders").Request())
+
+	expect.That(t,
+		is.EqualTo(w.Code, http.StatusOK),
+		is.EqualTo(strings.TrimSpace(w.Body.String()), "null"),
+	)
+}
+
+func TestDecodeRequest_form(t *testing.T) {
+	type login struct {
+		User     string `form:"user"`
+		Remember bool   `form:"remember"`
+	}
+
+	r := requestbuilder.Post("/login").
+		AddHeader("Content-Type", "application/x-www-form-urlencoded").
+		Body(strings.NewReader("user=jdoe&remember=true")).
+		Request()
+
+	got, err := DecodeRequest[login](r)
+	expect.That(t,
+		is.NoError(err),
+		is.EqualTo(got, login{User: "jdoe", Remember: true}),
+	)
+}
+
+func TestTyped_validation(t *testing.T) {
+	type createUser struct {
+		Name string `json:"name" validate:"required"`
+	}
+
+	mux := NewServeMux()
+	mux.Handle("POST /users", Typed(func(ctx context.Context, req createUser) (createUser, error) {
+		return req, nil
+	}))
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, requestbuilder.Post("/users").
+		AddHeader("Content-Type", "application/json").
+		Body(strings.NewReader(`{}`)).
+		Request())
+
+	expect.That(t,
+		is.EqualTo(w.Code, http.StatusUnprocessableEntity),
+		is.EqualTo(w.Header().Get("Content-Type"), "application/problem+json"),
+		is.StringContaining(w.Body.String(), `"errors":[{"pointer":"#/name","rule":"required","detail":"is required"}]`),
+	)
+}
@@ -0,0 +1,105 @@
+// Package etag provides a HTTP middleware that adds entity tags to responses
+// and answers conditional requests with 304 Not Modified or 412 Precondition
+// Failed responses.
+package etag
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/halimath/httputils"
+	"github.com/halimath/httputils/bufferedresponse"
+	"github.com/halimath/httputils/response"
+)
+
+type middleware struct {
+	weak bool
+}
+
+// Option defines a mutator type to configure a middleware.
+type Option func(*middleware)
+
+// Weak is an [Option] that causes the middleware to generate weak entity tags.
+// Use weak entity tags if responses may differ in ways that are semantically
+// insignificant, i.e. due to compression.
+func Weak() Option {
+	return func(m *middleware) {
+		m.weak = true
+	}
+}
+
+// notModifiedHeaders lists the headers removed from 304 responses.
+var notModifiedHeaders = []string{"Content-Type", "Content-Length", "Content-Encoding"}
+
+// NewMiddleware creates a HTTP middleware that handles conditional GET and
+// HEAD requests. The response produced by the wrapped handler is buffered. If
+// it has status code 200 and no ETag header, an entity tag is computed from
+// the body using [response.ComputeETag]. Handlers may provide precomputed
+// validators by setting the ETag and Last-Modified headers (see
+// [response.ETag] and [response.LastModified]).
+//
+// The request's conditional headers are evaluated against these validators
+// using [response.EvaluatePreconditions]. Depending on the result, the
+// buffered response is replaced by a 304 Not Modified response carrying the
+// response's headers except for Content-Type, Content-Length and
+// Content-Encoding or by a 412 Precondition Failed problem details response.
+//
+// Requests using other methods are passed through unmodified; use
+// [response.GuardWrite] to handle preconditions of state changing requests.
+func NewMiddleware(opts ...Option) httputils.Middleware {
+	mw := &middleware{}
+
+	for _, opt := range opts {
+		opt(mw)
+	}
+
+	return func(handler http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				handler.ServeHTTP(w, r)
+				return
+			}
+
+			var buf bufferedresponse.ResponseWriter
+			handler.ServeHTTP(&buf, r)
+
+			if buf.StatusCode() != 0 && buf.StatusCode() != http.StatusOK {
+				buf.WriteTo(w)
+				return
+			}
+
+			etag := buf.Header().Get("ETag")
+			if etag == "" {
+				etag = response.ComputeETag(buf.Body(), mw.weak)
+				buf.Header().Set("ETag", etag)
+			}
+
+			var lastModified time.Time
+			if lm := buf.Header().Get("Last-Modified"); lm != "" {
+				lastModified, _ = http.ParseTime(lm)
+			}
+
+			switch response.EvaluatePreconditions(r, etag, lastModified) {
+			case http.StatusNotModified:
+				h := w.Header()
+				for k, vals := range buf.Header() {
+					h[k] = vals
+				}
+				for _, k := range notModifiedHeaders {
+					h.Del(k)
+				}
+				w.WriteHeader(http.StatusNotModified)
+
+			case http.StatusPreconditionFailed:
+				response.Problem(w, r, response.ProblemDetails{
+					Type:   "about:blank",
+					Title:  http.StatusText(http.StatusPreconditionFailed),
+					Status: http.StatusPreconditionFailed,
+				})
+
+			default:
+				buf.WriteTo(w)
+			}
+		})
+	}
+}
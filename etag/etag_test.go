@@ -0,0 +1,94 @@
+package etag
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/halimath/expect"
+	"github.com/halimath/expect/is"
+	"github.com/halimath/httputils/requestbuilder"
+	"github.com/halimath/httputils/response"
+)
+
+func TestNewMiddleware(t *testing.T) {
+	modified := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/versioned":
+			response.PlainText(w, r, "hello", response.ETag("v1"), response.LastModified(modified))
+		case "/missing":
+			response.NotFound(w, r)
+		default:
+			response.PlainText(w, r, "hello", response.SetHeader("Cache-Control", "max-age=60", true))
+		}
+	})
+
+	serve := func(mw http.Handler, r *http.Request) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		mw.ServeHTTP(w, r)
+		return w
+	}
+
+	strong := NewMiddleware()(h)
+	computed := response.ComputeETag([]byte("hello"), false)
+
+	t.Run("computed", func(t *testing.T) {
+		w := serve(strong, requestbuilder.Get("/").Request())
+		expect.That(t,
+			is.EqualTo(w.Code, http.StatusOK),
+			is.EqualTo(w.Header().Get("ETag"), computed),
+			is.EqualTo(w.Body.String(), "hello"),
+		)
+	})
+
+	t.Run("weak", func(t *testing.T) {
+		w := serve(NewMiddleware(Weak())(h), requestbuilder.Get("/").Request())
+		expect.That(t, is.EqualTo(w.Header().Get("ETag"), "W/"+computed))
+	})
+
+	t.Run("not_modified", func(t *testing.T) {
+		w := serve(strong, requestbuilder.Get("/").AddHeader("If-None-Match", computed).Request())
+		expect.That(t,
+			is.EqualTo(w.Code, http.StatusNotModified),
+			is.EqualTo(w.Header().Get("ETag"), computed),
+			is.EqualTo(w.Header().Get("Cache-Control"), "max-age=60"),
+			is.EqualTo(w.Header().Get("Content-Type"), ""),
+			is.EqualTo(w.Body.Len(), 0),
+		)
+	})
+
+	t.Run("precomputed", func(t *testing.T) {
+		w := serve(strong, requestbuilder.Get("/versioned").AddHeader("If-Modified-Since", modified.Format(http.TimeFormat)).Request())
+		expect.That(t,
+			is.EqualTo(w.Code, http.StatusNotModified),
+			is.EqualTo(w.Header().Get("ETag"), `"v1"`),
+		)
+	})
+
+	t.Run("precondition_failed", func(t *testing.T) {
+		w := serve(strong, requestbuilder.Get("/versioned").AddHeader("If-Match", `"v0"`).Request())
+		expect.That(t,
+			is.EqualTo(w.Code, http.StatusPreconditionFailed),
+			is.EqualTo(w.Header().Get("Content-Type"), "application/problem+json"),
+		)
+	})
+
+	t.Run("error_status", func(t *testing.T) {
+		w := serve(strong, requestbuilder.Get("/missing").AddHeader("If-None-Match", "*").Request())
+		expect.That(t,
+			is.EqualTo(w.Code, http.StatusNotFound),
+			is.EqualTo(w.Header().Get("ETag"), ""),
+		)
+	})
+
+	t.Run("unsafe_method", func(t *testing.T) {
+		w := serve(strong, requestbuilder.Post("/").AddHeader("If-None-Match", "*").Request())
+		expect.That(t,
+			is.EqualTo(w.Code, http.StatusOK),
+			is.EqualTo(w.Header().Get("ETag"), ""),
+		)
+	})
+}
@@ -0,0 +1,16 @@
+package etag_test
+
+import (
+	"net/http"
+
+	"github.com/halimath/httputils/etag"
+	"github.com/halimath/httputils/response"
+)
+
+func ExampleNewMiddleware() {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response.JSON(w, r, map[string]string{"greeting": "hello"})
+	})
+
+	http.ListenAndServe(":8080", etag.NewMiddleware()(handler))
+}
@@ -5,7 +5,10 @@
 // more utilities.
 package httputils
 
-import "net/http"
+import (
+	"net/http"
+	"strings"
+)
 
 // Middleware defines the common function signature for HTTP middlewares as
 // a golang type.
@@ -28,3 +31,33 @@ func Compose(middlewares ...Middleware) Middleware {
 		return h
 	}
 }
+
+// AddVary adds names to the Vary header of h. Names already listed are
+// skipped (compared case-insensitively) so middlewares and handlers can
+// declare the request headers they consult without producing duplicates. If h
+// already varies on * (any header), nothing is added.
+func AddVary(h http.Header, names ...string) {
+	var existing []string
+	for _, v := range h.Values("Vary") {
+		for _, n := range strings.Split(v, ",") {
+			if n = strings.TrimSpace(n); n != "" {
+				existing = append(existing, n)
+			}
+		}
+	}
+
+	for _, name := range names {
+		found := false
+		for _, e := range existing {
+			if e == "*" || strings.EqualFold(e, name) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			h.Add("Vary", name)
+			existing = append(existing, name)
+		}
+	}
+}
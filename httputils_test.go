@@ -55,3 +55,25 @@ func TestCompose(t *testing.T) {
 			"mw2-after", // outer
 		}))
 }
+
+func TestAddVary(t *testing.T) {
+	t.Run("empty", func(t *testing.T) {
+		h := make(http.Header)
+		AddVary(h, "Accept", "Origin")
+		expect.That(t, is.DeepEqualTo(h.Values("Vary"), []string{"Accept", "Origin"}))
+	})
+
+	t.Run("duplicates", func(t *testing.T) {
+		h := make(http.Header)
+		h.Set("Vary", "accept, Accept-Encoding")
+		AddVary(h, "Accept", "Origin", "origin")
+		expect.That(t, is.DeepEqualTo(h.Values("Vary"), []string{"accept, Accept-Encoding", "Origin"}))
+	})
+
+	t.Run("wildcard", func(t *testing.T) {
+		h := make(http.Header)
+		h.Set("Vary", "*")
+		AddVary(h, "Accept")
+		expect.That(t, is.DeepEqualTo(h.Values("Vary"), []string{"*"}))
+	})
+}
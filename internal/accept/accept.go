@@ -0,0 +1,115 @@
+// Package accept implements server-driven content negotiation based on the
+// Accept request header as specified in RFC 9110 section 12.5.1
+// (https://www.rfc-editor.org/rfc/rfc9110#section-12.5.1).
+package accept
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/halimath/httputils/internal/valuecomponents"
+)
+
+// MediaRange implements a single media range of an Accept header.
+type MediaRange struct {
+	Type    string
+	Subtype string
+	Params  map[string]string
+	Q       float64
+}
+
+// Parse parses the Accept header value h into a list of media ranges using
+// the field value parser from [valuecomponents]. Elements that are not a valid
+// media range are ignored. An error is returned if h is malformed.
+func Parse(h string) ([]MediaRange, error) {
+	vals, err := valuecomponents.ParseValueList(h)
+	if err != nil {
+		return nil, err
+	}
+
+	ranges := make([]MediaRange, 0, len(vals))
+
+	for _, v := range vals {
+		typ, subtype, ok := strings.Cut(v.Primary, "/")
+		if !ok {
+			continue
+		}
+
+		mr := MediaRange{Type: typ, Subtype: subtype, Params: make(map[string]string, len(v.Pairs)), Q: 1}
+		for k, p := range v.Pairs {
+			if strings.EqualFold(k, "q") {
+				if q, err := strconv.ParseFloat(p, 64); err == nil && q >= 0 && q <= 1 {
+					mr.Q = q
+				}
+				continue
+			}
+			mr.Params[strings.ToLower(k)] = p
+		}
+
+		ranges = append(ranges, mr)
+	}
+
+	return ranges, nil
+}
+
+// match returns the specificity of mr matching the media type typ/subtype or
+// -1 if mr does not match.
+func (mr MediaRange) match(typ, subtype string) int {
+	switch {
+	case mr.Type == "*" && mr.Subtype == "*":
+		return 0
+	case !strings.EqualFold(mr.Type, typ):
+		return -1
+	case mr.Subtype == "*":
+		return 1
+	case strings.EqualFold(mr.Subtype, subtype):
+		return 2 + len(mr.Params)
+	default:
+		return -1
+	}
+}
+
+// Quality returns the quality value assigned to the media type mediaType by
+// ranges. The most specific matching range defines the quality. If no range
+// matches, 0 is returned.
+func Quality(ranges []MediaRange, mediaType string) float64 {
+	typ, subtype, _ := strings.Cut(mediaType, "/")
+
+	best, q := -1, 0.0
+	for _, mr := range ranges {
+		if s := mr.match(typ, subtype); s > best {
+			best, q = s, mr.Q
+		}
+	}
+
+	return q
+}
+
+// Negotiate selects the offer best matching the Accept header value h. offers
+// are given in order of the server's preference, which decides between offers
+// of equal quality. If h is empty, the first offer is returned. If no offer is
+// acceptable, Negotiate returns false. A malformed header is treated as if it
+// was absent.
+func Negotiate(h string, offers ...string) (string, bool) {
+	if len(offers) == 0 {
+		return "", false
+	}
+
+	if strings.TrimSpace(h) == "" {
+		return offers[0], true
+	}
+
+	ranges, err := Parse(h)
+	if err != nil {
+		return offers[0], true
+	}
+
+	best, bestQ := "", 0.0
+	for _, o := range offers {
+		if q := Quality(ranges, o); q > bestQ {
+			best, bestQ = o, q
+		}
+	}
+
+	return best, bestQ > 0
+}
@@ -0,0 +1,70 @@
+package accept
+
+import (
+	"testing"
+
+	"github.com/halimath/expect"
+	"github.com/halimath/expect/is"
+)
+
+func TestNegotiate(t *testing.T) {
+	offers := []string{"application/problem+json", "text/html", "text/plain"}
+
+	tests := map[string]struct {
+		header string
+		want   string
+		ok     bool
+	}{
+		"empty":            {"", "application/problem+json", true},
+		"wildcard":         {"*/*", "application/problem+json", true},
+		"browser":          {"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", "text/html", true},
+		"subtype_wildcard": {"text/*", "text/html", true},
+		"q_values":         {"text/html;q=0.5, text/plain", "text/plain", true},
+		"specific_wins":    {"text/*;q=0.9, text/html;q=0", "text/plain", true},
+		"not_acceptable":   {"image/png", "", false},
+		"no_media_range":   {"foo, text/plain", "text/plain", true},
+		"malformed":        {"??, text/plain", "application/problem+json", true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, ok := Negotiate(test.header, offers...)
+			expect.That(t,
+				is.EqualTo(got, test.want),
+				is.EqualTo(ok, test.ok),
+			)
+		})
+	}
+}
+
+func TestNegotiateLanguage(t *testing.T) {
+	offers := []string{"en", "de", "de-CH", "fr-CA"}
+
+	tests := map[string]struct {
+		header string
+		want   string
+		ok     bool
+	}{
+		"empty":      {"", "", false},
+		"exact":      {"de", "de", true},
+		"case":       {"DE-ch", "de-CH", true},
+		"fallback":   {"en-US, de;q=0.8", "en", true},
+		"prefix":     {"fr", "fr-CA", true},
+		"q_values":   {"de;q=0.5, en;q=0.4", "de", true},
+		"wildcard":   {"it, *;q=0.1", "en", true},
+		"excluded":   {"de;q=0", "", false},
+		"no_match":   {"it", "", false},
+		"malformed":  {"??", "", false},
+		"no_partial": {"d", "", false},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, ok := NegotiateLanguage(test.header, offers...)
+			expect.That(t,
+				is.EqualTo(got, test.want),
+				is.EqualTo(ok, test.ok),
+			)
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package accept
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/halimath/httputils/internal/valuecomponents"
+)
+
+// LanguageRange implements a single language range of an Accept-Language
+// header as specified in RFC 9110 section 12.5.4.
+type LanguageRange struct {
+	Tag string
+	Q   float64
+}
+
+// ParseLanguage parses the Accept-Language header value h into a list of
+// language ranges. An error is returned if h is malformed.
+func ParseLanguage(h string) ([]LanguageRange, error) {
+	vals, err := valuecomponents.ParseValueList(h)
+	if err != nil {
+		return nil, err
+	}
+
+	ranges := make([]LanguageRange, 0, len(vals))
+
+	for _, v := range vals {
+		lr := LanguageRange{Tag: v.Primary, Q: 1}
+		for k, p := range v.Pairs {
+			if strings.EqualFold(k, "q") {
+				if q, err := strconv.ParseFloat(p, 64); err == nil && q >= 0 && q <= 1 {
+					lr.Q = q
+				}
+			}
+		}
+
+		ranges = append(ranges, lr)
+	}
+
+	return ranges, nil
+}
+
+// match returns the specificity of lr matching the language tag tag or -1 if
+// lr does not match. A range matches tags it is a prefix of (i.e. de matches
+// de-CH) as well as tags that are a prefix of the range (i.e. de-CH falls back
+// to de) as defined by the filtering and lookup schemes of RFC 4647.
+func (lr LanguageRange) match(tag string) int {
+	switch {
+	case lr.Tag == "*":
+		return 0
+	case strings.EqualFold(lr.Tag, tag):
+		return 3
+	case hasTagPrefix(tag, lr.Tag):
+		return 2
+	case hasTagPrefix(lr.Tag, tag):
+		return 1
+	default:
+		return -1
+	}
+}
+
+// hasTagPrefix reports whether prefix is a prefix of tag ending at a subtag
+// boundary.
+func hasTagPrefix(tag, prefix string) bool {
+	return len(tag) > len(prefix) && tag[len(prefix)] == '-' && strings.EqualFold(tag[:len(prefix)], prefix)
+}
+
+// NegotiateLanguage selects the language tag from offers best matching the
+// Accept-Language header value h. Among offers of equal quality the one
+// matched most specifically wins; offers are given in order of the server's
+// preference, which decides remaining ties. If h is empty or malformed or no
+// offer is acceptable, NegotiateLanguage returns false, so the caller can fall
+// back to its default language.
+func NegotiateLanguage(h string, offers ...string) (string, bool) {
+	ranges, err := ParseLanguage(h)
+	if err != nil {
+		return "", false
+	}
+
+	best, bestQ, bestSpec := "", 0.0, -1
+	for _, o := range offers {
+		spec, q := -1, 0.0
+		for _, lr := range ranges {
+			if s := lr.match(o); s > spec {
+				spec, q = s, lr.Q
+			}
+		}
+
+		if q > bestQ || (q > 0 && q == bestQ && spec > bestSpec) {
+			best, bestQ, bestSpec = o, q, spec
+		}
+	}
+
+	return best, bestQ > 0
+}
@@ -0,0 +1,33 @@
+// Package secure contains helpers to determine whether a request has been
+// issued over a secure transport.
+package secure
+
+import (
+	"net/http"
+	"strings"
+)
+
+// IsSecureRequest reports whether r has been received via HTTPS. It considers
+// a direct TLS connection as well as the Forwarded and X-Forwarded-Proto headers
+// set by reverse proxies.
+func IsSecureRequest(r *http.Request) bool {
+	// Direct TLS connection
+	if r.TLS != nil {
+		return true
+	}
+
+	// Forwarded request header
+	if forwarded := r.Header.Get("Forwarded"); forwarded != "" {
+		if strings.Contains(forwarded, "proto=https") {
+			return true
+		}
+	}
+
+	// Legacy X-Forwarded-Proto header
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto == "https" {
+		return true
+	}
+
+	// Parsed request URL
+	return r.URL.Scheme == "https"
+}
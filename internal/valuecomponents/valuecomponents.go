@@ -21,7 +21,9 @@ type Value struct {
 type ValueList []Value
 
 // ParseValueList parses the given string into a value list and returns
-// it or an error.
+// it or an error. Primary values may contain a single slash separating two
+// tokens, which allows parsing media types and ranges such as text/html or
+// */*.
 func ParseValueList(s string) (ValueList, error) {
 	i := 0
 
@@ -49,12 +51,23 @@ outer:
 			}
 
 			i += l
+
+			if l > 0 && s[i-1] != '"' && i < len(s) && s[i] == '/' {
+				// Primary values may be given as a media type or media range
+				// in the form type/subtype as used by i.e. Accept.
+				sub := ParseToken(s[i+1:])
+				if sub == "" {
+					return nil, fmt.Errorf("missing subtype at '%s'", s[i:])
+				}
+				v += "/" + sub
+				i += len(sub) + 1
+			}
+
 			i += consumeWhitespace(s[i:])
 
 			c, l := utf8.DecodeRuneInString(s[i:])
 			if c != '=' {
 				val.Primary = v
-				i += l
 			} else if c == '=' {
 				key := v
 
@@ -68,6 +68,24 @@ func TestParseFieldValueComponents(t *testing.T) {
 				},
 			},
 		},
+		`text/html,application/xml;q=0.9, */*;q=0.8`: {
+			Value{
+				Primary: "text/html",
+				Pairs:   map[string]string{},
+			},
+			Value{
+				Primary: "application/xml",
+				Pairs: map[string]string{
+					"q": "0.9",
+				},
+			},
+			Value{
+				Primary: "*/*",
+				Pairs: map[string]string{
+					"q": "0.8",
+				},
+			},
+		},
 		`proto=https; host=example.com; for=5.6.7.84, for=5.6.7.8; proto=http`: {
 			Value{
 				Primary: "",
@@ -95,3 +113,15 @@ func TestParseFieldValueComponents(t *testing.T) {
 		)
 	}
 }
+
+func TestParseValueList_delimitersWithoutWhitespace(t *testing.T) {
+	got, err := ParseValueList(`gzip,deflate;q=0.5,br`)
+	expect.That(t,
+		expect.FailNow(is.NoError(err)),
+		is.DeepEqualTo(got, ValueList{
+			{Primary: "gzip", Pairs: map[string]string{}},
+			{Primary: "deflate", Pairs: map[string]string{"q": "0.5"}},
+			{Primary: "br", Pairs: map[string]string{}},
+		}),
+	)
+}
@@ -0,0 +1,37 @@
+package openapi_test
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/halimath/httputils/auth"
+	"github.com/halimath/httputils/errmux"
+	"github.com/halimath/httputils/openapi"
+)
+
+func Example() {
+	type getOrderRequest struct {
+		ID int `path:"id"`
+	}
+
+	type order struct {
+		ID    int    `json:"id"`
+		State string `json:"state" validate:"enum=open|closed"`
+	}
+
+	mux := errmux.NewServeMux()
+
+	mux.Handle("GET /orders/{id}", errmux.Typed(func(ctx context.Context, req getOrderRequest) (order, error) {
+		return order{ID: req.ID, State: "open"}, nil
+	}),
+		errmux.Summary("Get an order"),
+		errmux.Tags("orders"),
+		errmux.Security(auth.AuthenticationChallenge{Scheme: "Bearer", Realm: "orders"}),
+	)
+
+	info := openapi.Info{Title: "Orders API", Version: "1.0.0"}
+	mux.Handle("GET /openapi.json", openapi.Handler(mux, info), errmux.Hidden())
+	mux.Handle("GET /openapi.yaml", openapi.Handler(mux, info), errmux.Hidden())
+
+	http.ListenAndServe(":8080", mux)
+}
@@ -0,0 +1,316 @@
+// Package openapi generates [OpenAPI 3.1] documents from the routes registered
+// with an [errmux.ServeMux].
+//
+// Every route registered with a method is documented as an operation. Routes
+// registered without a method as well as routes marked with [errmux.Hidden]
+// are skipped. Metadata is taken from the [errmux.RouteInfo] of each route,
+// which is populated with [errmux.RouteOption]s such as [errmux.Summary],
+// [errmux.Tags], [errmux.Response] or [errmux.Security]. Routes using a
+// handler created with [errmux.Typed] document their request and response
+// types automatically.
+//
+// JSON schemas are derived from Go types using reflection. Named struct types
+// are added to the document's components and referenced. Field names are taken
+// from the json struct tag; validation rules given with the validate struct tag
+// (see package [validate]) are converted to the corresponding schema keywords.
+// Fields of request types tagged with path, query or header (see
+// [errmux.Typed]) are documented as parameters.
+//
+// [OpenAPI 3.1]: https://spec.openapis.org/oas/v3.1.0
+// [validate]: https://pkg.go.dev/github.com/halimath/httputils/validate
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/halimath/httputils/auth"
+	"github.com/halimath/httputils/errmux"
+	"github.com/halimath/httputils/response"
+)
+
+// Version contains the OpenAPI version of generated documents.
+const Version = "3.1.0"
+
+const (
+	// ContentTypeJSON defines the content type used to serve JSON documents.
+	ContentTypeJSON = "application/json"
+
+	// ContentTypeYAML defines the content type used to serve YAML documents.
+	ContentTypeYAML = "application/yaml"
+)
+
+// Document defines an OpenAPI document. Only the parts of the specification
+// needed to document routes registered with an [errmux.ServeMux] are modeled.
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Servers    []Server            `json:"servers,omitempty"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components *Components         `json:"components,omitempty"`
+}
+
+// Info contains the API's metadata.
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// Server defines a server hosting the API.
+type Server struct {
+	URL         string `json:"url"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem maps lower case HTTP methods to operations.
+type PathItem map[string]*Operation
+
+// Operation describes a single API operation.
+type Operation struct {
+	OperationID string                `json:"operationId,omitempty"`
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Deprecated  bool                  `json:"deprecated,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]Response   `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+// Parameter describes a single path, query or header parameter.
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required,omitempty"`
+	Schema   *Schema `json:"schema"`
+}
+
+// RequestBody describes an operation's request body.
+type RequestBody struct {
+	Required bool                 `json:"required,omitempty"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// MediaType describes the content of a request or response body.
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Response describes a single response of an operation.
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// Components holds reusable schemas and security schemes.
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas,omitempty"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+// SecurityScheme defines a security scheme used by operations.
+type SecurityScheme struct {
+	Type        string `json:"type"`
+	Scheme      string `json:"scheme,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// JSON returns d encoded as indented JSON.
+func (d *Document) JSON() ([]byte, error) {
+	return json.MarshalIndent(d, "", "  ")
+}
+
+// YAML returns d encoded as YAML.
+func (d *Document) YAML() ([]byte, error) {
+	data, err := json.Marshal(d)
+	if err != nil {
+		return nil, err
+	}
+	return jsonToYAML(data)
+}
+
+// --
+
+// Generate generates a Document describing routes.
+func Generate(info Info, routes []errmux.RouteInfo) *Document {
+	g := newSchemaGenerator()
+
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Paths:   make(map[string]PathItem),
+	}
+
+	components := &Components{}
+
+	// ProblemDetails implements json.Marshaler to serialize extension members
+	// inline, so its schema is derived from the struct directly and allows
+	// additional properties.
+	problemSchema := g.ref(reflect.TypeFor[response.ProblemDetails]())
+	g.schemas[strings.TrimPrefix(problemSchema.Ref, "#/components/schemas/")].AdditionalProperties = &Schema{}
+
+	for _, rt := range routes {
+		if rt.Hidden || rt.Method == "" {
+			continue
+		}
+
+		op := &Operation{
+			OperationID: rt.OperationID,
+			Summary:     rt.Summary,
+			Description: rt.Description,
+			Tags:        rt.Tags,
+			Deprecated:  rt.Deprecated,
+			Responses:   make(map[string]Response),
+		}
+
+		if rt.Request != nil {
+			op.Parameters = g.parameters(rt.Request)
+
+			if rt.Method != http.MethodGet && rt.Method != http.MethodHead {
+				if s := g.bodySchema(rt.Request); s != nil {
+					op.RequestBody = &RequestBody{
+						Required: true,
+						Content:  map[string]MediaType{ContentTypeJSON: {Schema: s}},
+					}
+				}
+			}
+		}
+
+		for _, p := range pathParameters(rt.Path) {
+			if !slices.ContainsFunc(op.Parameters, func(op Parameter) bool { return op.In == "path" && op.Name == p }) {
+				op.Parameters = append(op.Parameters, Parameter{Name: p, In: "path", Required: true, Schema: &Schema{Type: "string"}})
+			}
+		}
+
+		for _, res := range rt.Responses {
+			r := Response{Description: res.Description}
+			if r.Description == "" {
+				r.Description = http.StatusText(res.Status)
+			}
+			if res.Type != nil {
+				r.Content = map[string]MediaType{ContentTypeJSON: {Schema: g.schemaFor(res.Type)}}
+			}
+			op.Responses[strconv.Itoa(res.Status)] = r
+		}
+
+		op.Responses["default"] = Response{
+			Description: "Error",
+			Content:     map[string]MediaType{"application/problem+json": {Schema: problemSchema}},
+		}
+
+		for _, c := range rt.Security {
+			name, scheme := securityScheme(c)
+			if components.SecuritySchemes == nil {
+				components.SecuritySchemes = make(map[string]SecurityScheme)
+			}
+			components.SecuritySchemes[name] = scheme
+			op.Security = append(op.Security, map[string][]string{name: {}})
+		}
+
+		path := openAPIPath(rt.Path)
+		item, ok := doc.Paths[path]
+		if !ok {
+			item = make(PathItem)
+			doc.Paths[path] = item
+		}
+		item[strings.ToLower(rt.Method)] = op
+	}
+
+	components.Schemas = g.schemas
+	doc.Components = components
+
+	return doc
+}
+
+// openAPIPath converts the path of a [http.ServeMux] pattern to an OpenAPI
+// path template.
+func openAPIPath(path string) string {
+	path = strings.ReplaceAll(path, "{$}", "")
+	return strings.ReplaceAll(path, "...}", "}")
+}
+
+// pathParameters returns the names of all wildcards contained in path.
+func pathParameters(path string) []string {
+	var names []string
+	for {
+		start := strings.IndexByte(path, '{')
+		if start < 0 {
+			return names
+		}
+		end := strings.IndexByte(path[start:], '}')
+		if end < 0 {
+			return names
+		}
+		name := strings.TrimSuffix(path[start+1:start+end], "...")
+		if name != "$" {
+			names = append(names, name)
+		}
+		path = path[start+end+1:]
+	}
+}
+
+// securityScheme converts c into a named security scheme.
+func securityScheme(c auth.AuthenticationChallenge) (string, SecurityScheme) {
+	scheme := strings.ToLower(c.Scheme)
+	return scheme, SecurityScheme{
+		Type:        "http",
+		Scheme:      scheme,
+		Description: c.Realm,
+	}
+}
+
+// --
+
+// RouteLister is implemented by types that list their routes, such as
+// [errmux.ServeMux].
+type RouteLister interface {
+	Routes() []errmux.RouteInfo
+}
+
+// Handler creates an [errmux.Handler] that serves a Document generated from
+// the routes of routes. The document is generated for every request, so routes
+// registered after Handler has been called are included. The document is
+// served as YAML if the request's path ends with .yaml or .yml or if the
+// request's Accept header asks for YAML; otherwise it is served as JSON.
+//
+// Register the handler with [errmux.Hidden] to exclude it from the document:
+//
+//	mux.Handle("GET /openapi.json", openapi.Handler(mux, info), errmux.Hidden())
+func Handler(routes RouteLister, info Info) errmux.Handler {
+	return errmux.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		doc := Generate(info, routes.Routes())
+
+		contentType := ContentTypeJSON
+		marshal := doc.JSON
+
+		if wantsYAML(r) {
+			contentType = ContentTypeYAML
+			marshal = doc.YAML
+		}
+
+		data, err := marshal()
+		if err != nil {
+			return err
+		}
+
+		return response.Send(w, r,
+			response.SetHeader("Content-Type", contentType, true),
+			response.SetHeader("Content-Length", strconv.Itoa(len(data)), true),
+			response.WriteBody(data),
+		)
+	})
+}
+
+func wantsYAML(r *http.Request) bool {
+	if strings.HasSuffix(r.URL.Path, ".yaml") || strings.HasSuffix(r.URL.Path, ".yml") {
+		return true
+	}
+
+	return strings.Contains(r.Header.Get("Accept"), "yaml")
+}
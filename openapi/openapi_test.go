@@ -0,0 +1,171 @@
+package openapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/halimath/expect"
+	"github.com/halimath/expect/is"
+	"github.com/halimath/httputils/auth"
+	"github.com/halimath/httputils/errmux"
+	"github.com/halimath/httputils/requestbuilder"
+)
+
+type order struct {
+	ID    int         `json:"id"`
+	Items []orderItem `json:"items" validate:"required,max=10"`
+}
+
+type orderItem struct {
+	Name     string `json:"name" validate:"required,pattern=^[a-z]+$"`
+	Quantity int    `json:"quantity" validate:"min=1"`
+}
+
+type updateOrderRequest struct {
+	ID     int         `path:"id" json:"-"`
+	Tenant string      `header:"X-Tenant" json:"-" validate:"required"`
+	Items  []orderItem `json:"items"`
+}
+
+func newTestMux() *errmux.ServeMux {
+	mux := errmux.NewServeMux()
+
+	mux.Handle("PUT /orders/{id}", errmux.Typed(func(ctx context.Context, req updateOrderRequest) (order, error) {
+		return order{}, nil
+	}),
+		errmux.OperationID("updateOrder"),
+		errmux.Summary("Update an order"),
+		errmux.Tags("orders"),
+		errmux.Security(auth.AuthenticationChallenge{Scheme: "Bearer", Realm: "api"}),
+	)
+
+	mux.HandleFunc("DELETE /orders/{id}", func(w http.ResponseWriter, r *http.Request) error { return nil },
+		errmux.EmptyResponse(http.StatusNoContent, "Order deleted"),
+	)
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) error { return nil })
+
+	mux.Handle("GET /openapi.json", Handler(mux, Info{Title: "Orders", Version: "1.0"}), errmux.Hidden())
+	mux.Handle("GET /openapi.yaml", Handler(mux, Info{Title: "Orders", Version: "1.0"}), errmux.Hidden())
+
+	return mux
+}
+
+func TestGenerate(t *testing.T) {
+	doc := Generate(Info{Title: "Orders", Version: "1.0"}, newTestMux().Routes())
+
+	expect.That(t,
+		is.EqualTo(doc.OpenAPI, "3.1.0"),
+		is.MapOfLen(doc.Paths, 1),
+		is.MapOfLen(doc.Paths["/orders/{id}"], 2),
+	)
+
+	put := doc.Paths["/orders/{id}"]["put"]
+	expect.That(t,
+		is.EqualTo(put.OperationID, "updateOrder"),
+		is.EqualTo(put.Summary, "Update an order"),
+		is.DeepEqualTo(put.Tags, []string{"orders"}),
+		is.DeepEqualTo(put.Security, []map[string][]string{{"bearer": {}}}),
+		is.DeepEqualTo(put.Parameters, []Parameter{
+			{Name: "id", In: "path", Required: true, Schema: &Schema{Type: "integer", Format: "int64"}},
+			{Name: "X-Tenant", In: "header", Required: true, Schema: &Schema{Type: "string"}},
+		}),
+		is.DeepEqualTo(put.RequestBody.Content["application/json"].Schema, &Schema{
+			Type: "object",
+			Properties: map[string]*Schema{
+				"items": {Type: "array", Items: &Schema{Ref: "#/components/schemas/orderItem"}},
+			},
+		}),
+		is.DeepEqualTo(put.Responses["200"].Content["application/json"].Schema, &Schema{Ref: "#/components/schemas/order"}),
+		is.EqualTo(put.Responses["default"].Content["application/problem+json"].Schema.Ref, "#/components/schemas/ProblemDetails"),
+	)
+
+	del := doc.Paths["/orders/{id}"]["delete"]
+	expect.That(t,
+		is.DeepEqualTo(del.Parameters, []Parameter{{Name: "id", In: "path", Required: true, Schema: &Schema{Type: "string"}}}),
+		is.DeepEqualTo(del.Responses["204"], Response{Description: "Order deleted"}),
+	)
+
+	ten := 10
+	minQuantity := float64(1)
+	expect.That(t,
+		is.DeepEqualTo(doc.Components.SecuritySchemes, map[string]SecurityScheme{
+			"bearer": {Type: "http", Scheme: "bearer", Description: "api"},
+		}),
+		is.DeepEqualTo(doc.Components.Schemas["order"], &Schema{
+			Type: "object",
+			Properties: map[string]*Schema{
+				"id":    {Type: "integer", Format: "int64"},
+				"items": {Type: "array", Items: &Schema{Ref: "#/components/schemas/orderItem"}, MaxItems: &ten},
+			},
+			Required: []string{"items"},
+		}),
+		is.DeepEqualTo(doc.Components.Schemas["orderItem"], &Schema{
+			Type: "object",
+			Properties: map[string]*Schema{
+				"name":     {Type: "string", Pattern: "^[a-z]+$"},
+				"quantity": {Type: "integer", Format: "int64", Minimum: &minQuantity},
+			},
+			Required: []string{"name"},
+		}),
+	)
+}
+
+func TestHandler(t *testing.T) {
+	mux := newTestMux()
+
+	t.Run("json", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, requestbuilder.Get("/openapi.json").Request())
+
+		var doc Document
+		err := json.Unmarshal(w.Body.Bytes(), &doc)
+
+		expect.That(t,
+			is.EqualTo(w.Code, http.StatusOK),
+			is.EqualTo(w.Header().Get("Content-Type"), "application/json"),
+			is.NoError(err),
+			is.EqualTo(doc.Info.Title, "Orders"),
+		)
+	})
+
+	t.Run("yaml", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, requestbuilder.Get("/openapi.yaml").Request())
+
+		expect.That(t,
+			is.EqualTo(w.Code, http.StatusOK),
+			is.EqualTo(w.Header().Get("Content-Type"), "application/yaml"),
+			is.StringWithPrefix(w.Body.String(), "openapi: \"3.1.0\"\ninfo:\n  title: Orders\n"),
+		)
+	})
+}
+
+func TestJSONToYAML(t *testing.T) {
+	got, err := jsonToYAML([]byte(`{
+		"a": "about:blank",
+		"b": [1, {"x": true, "y": null}, [], ["z"]],
+		"c": {},
+		"200": {"$ref": "#/components/schemas/X"},
+		"d": "yes"
+	}`))
+
+	expect.That(t,
+		is.NoError(err),
+		is.EqualTo(string(got), `a: "about:blank"
+b:
+  - 1
+  - x: true
+    "y": null
+  - []
+  - - z
+c: {}
+"200":
+  $ref: "#/components/schemas/X"
+d: "yes"
+`),
+	)
+}
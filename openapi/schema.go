@@ -0,0 +1,347 @@
+package openapi
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schema defines a JSON schema as used by OpenAPI 3.1. Only the keywords
+// derived from Go types are modeled.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+}
+
+var (
+	timeType            = reflect.TypeFor[time.Time]()
+	jsonMarshalerType   = reflect.TypeFor[json.Marshaler]()
+	textMarshalerType   = reflect.TypeFor[encoding.TextMarshaler]()
+	parameterSourceTags = []string{"path", "query", "header"}
+)
+
+// schemaGenerator derives schemas from Go types. Named struct types are
+// collected in schemas and referenced.
+type schemaGenerator struct {
+	schemas map[string]*Schema
+	names   map[reflect.Type]string
+}
+
+func newSchemaGenerator() *schemaGenerator {
+	return &schemaGenerator{
+		schemas: make(map[string]*Schema),
+		names:   make(map[reflect.Type]string),
+	}
+}
+
+// schemaFor returns the schema for t.
+func (g *schemaGenerator) schemaFor(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType):
+		return &Schema{}
+	case t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType):
+		return &Schema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64, reflect.Uintptr:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: g.schemaFor(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schemaFor(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t, false)
+		}
+		return g.ref(t)
+	default:
+		return &Schema{}
+	}
+}
+
+// ref returns a schema referencing the component schema for the named struct
+// type t. The component schema is generated on first use.
+func (g *schemaGenerator) ref(t reflect.Type) *Schema {
+	name, ok := g.names[t]
+	if !ok {
+		name = g.componentName(t)
+		g.names[t] = name
+		// Register a placeholder first to support recursive types.
+		g.schemas[name] = &Schema{}
+		*g.schemas[name] = *g.structSchema(t, false)
+	}
+
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// componentName returns a unique component name for t.
+func (g *schemaGenerator) componentName(t reflect.Type) string {
+	base := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '_' || r == '-' {
+			return r
+		}
+		return '_'
+	}, t.Name())
+
+	name := base
+	for i := 2; ; i++ {
+		if _, taken := g.schemas[name]; !taken {
+			return name
+		}
+		name = base + strconv.Itoa(i)
+	}
+}
+
+// structSchema creates an object schema for the struct type t. If skipParams
+// is true, fields documented as parameters are excluded.
+func (g *schemaGenerator) structSchema(t reflect.Type, skipParams bool) *Schema {
+	s := &Schema{Type: "object"}
+	g.addFields(s, t, skipParams)
+	return s
+}
+
+func (g *schemaGenerator) addFields(s *Schema, t reflect.Type, skipParams bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		if skipParams && isParameter(f) {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" && opts == "" {
+			continue
+		}
+
+		if f.Anonymous && name == "" && indirect(f.Type).Kind() == reflect.Struct {
+			g.addFields(s, indirect(f.Type), skipParams)
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+
+		fs := g.schemaFor(f.Type)
+		if applyValidationRules(fs, f, indirect(f.Type).Kind()) {
+			s.Required = append(s.Required, name)
+		}
+
+		if s.Properties == nil {
+			s.Properties = make(map[string]*Schema)
+		}
+		s.Properties[name] = fs
+	}
+}
+
+// bodySchema returns the schema of the request body for the request type t or
+// nil if t carries no body.
+func (g *schemaGenerator) bodySchema(t reflect.Type) *Schema {
+	t = indirect(t)
+	if t.Kind() != reflect.Struct || t == timeType {
+		return g.schemaFor(t)
+	}
+
+	if !hasParameters(t) {
+		s := g.schemaFor(t)
+		if s.Ref != "" && len(g.schemas[g.names[t]].Properties) == 0 {
+			return nil
+		}
+		return s
+	}
+
+	s := g.structSchema(t, true)
+	if len(s.Properties) == 0 {
+		return nil
+	}
+	return s
+}
+
+// parameters returns the parameters documented by the fields of the request
+// type t.
+func (g *schemaGenerator) parameters(t reflect.Type) []Parameter {
+	t = indirect(t)
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var params []Parameter
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		if f.Anonymous && indirect(f.Type).Kind() == reflect.Struct {
+			params = append(params, g.parameters(f.Type)...)
+			continue
+		}
+
+		for _, in := range parameterSourceTags {
+			name := f.Tag.Get(in)
+			if name == "" {
+				continue
+			}
+
+			s := g.schemaFor(f.Type)
+			required := applyValidationRules(s, f, indirect(f.Type).Kind())
+			params = append(params, Parameter{
+				Name:     name,
+				In:       in,
+				Required: required || in == "path",
+				Schema:   s,
+			})
+			break
+		}
+	}
+
+	return params
+}
+
+func isParameter(f reflect.StructField) bool {
+	for _, in := range parameterSourceTags {
+		if f.Tag.Get(in) != "" {
+			return true
+		}
+	}
+	return f.Tag.Get("form") != ""
+}
+
+func hasParameters(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if isParameter(f) {
+			return true
+		}
+		if f.Anonymous && indirect(f.Type).Kind() == reflect.Struct && hasParameters(indirect(f.Type)) {
+			return true
+		}
+	}
+	return false
+}
+
+func indirect(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
+
+// applyValidationRules converts the rules of f's validate struct tag into
+// schema keywords of s. kind is the kind of f's (dereferenced) type. It
+// reports whether f is required. Referenced schemas are left untouched.
+func applyValidationRules(s *Schema, f reflect.StructField, kind reflect.Kind) (required bool) {
+	rest := f.Tag.Get("validate")
+	for rest != "" {
+		var part string
+		if strings.HasPrefix(rest, "pattern=") {
+			part, rest = rest, ""
+		} else {
+			part, rest, _ = strings.Cut(rest, ",")
+		}
+
+		name, arg, _ := strings.Cut(strings.TrimSpace(part), "=")
+
+		if name == "required" {
+			required = true
+			continue
+		}
+
+		if s.Ref != "" {
+			continue
+		}
+
+		switch name {
+		case "min", "max", "len":
+			n, err := strconv.ParseFloat(arg, 64)
+			if err != nil {
+				continue
+			}
+			applyBound(s, name, n, kind)
+		case "pattern":
+			s.Pattern = arg
+		case "email":
+			s.Format = "email"
+		case "enum":
+			for _, v := range strings.Split(arg, "|") {
+				if s.Type == "integer" || s.Type == "number" {
+					if n, err := strconv.ParseFloat(v, 64); err == nil {
+						s.Enum = append(s.Enum, n)
+						continue
+					}
+				}
+				s.Enum = append(s.Enum, v)
+			}
+		}
+	}
+
+	return
+}
+
+func applyBound(s *Schema, rule string, n float64, kind reflect.Kind) {
+	i := int(n)
+
+	switch kind {
+	case reflect.String:
+		if rule != "max" {
+			s.MinLength = &i
+		}
+		if rule != "min" {
+			s.MaxLength = &i
+		}
+	case reflect.Slice, reflect.Array, reflect.Map:
+		if rule != "max" {
+			s.MinItems = &i
+		}
+		if rule != "min" {
+			s.MaxItems = &i
+		}
+	default:
+		switch rule {
+		case "min":
+			s.Minimum = &n
+		case "max":
+			s.Maximum = &n
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// yamlNode is an order preserving representation of a JSON value used to
+// emit YAML.
+type yamlNode struct {
+	// keys contains the keys of a mapping node.
+	keys []string
+
+	// children contains the values of a mapping or sequence node.
+	children []*yamlNode
+
+	// isMap and isSeq determine the node's kind. Nodes that are neither are
+	// scalars.
+	isMap, isSeq bool
+
+	// scalar contains the rendered value of a scalar node.
+	scalar string
+}
+
+// jsonToYAML converts the JSON document data to YAML. Key order is retained.
+func jsonToYAML(data []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	n, err := parseYAMLNode(dec)
+	if err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	if n.isMap || n.isSeq {
+		if len(n.children) == 0 {
+			b.WriteString(n.inline())
+			b.WriteByte('\n')
+		} else {
+			n.emit(&b, 0)
+		}
+	} else {
+		b.WriteString(n.scalar)
+		b.WriteByte('\n')
+	}
+
+	return []byte(b.String()), nil
+}
+
+func parseYAMLNode(dec *json.Decoder) (*yamlNode, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := tok.(type) {
+	case json.Delim:
+		n := &yamlNode{isMap: t == '{', isSeq: t == '['}
+		for dec.More() {
+			if n.isMap {
+				k, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				n.keys = append(n.keys, k.(string))
+			}
+
+			child, err := parseYAMLNode(dec)
+			if err != nil {
+				return nil, err
+			}
+			n.children = append(n.children, child)
+		}
+
+		// Consume the closing delimiter
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		return n, nil
+
+	case string:
+		return &yamlNode{scalar: yamlString(t)}, nil
+	case json.Number:
+		return &yamlNode{scalar: t.String()}, nil
+	case bool:
+		return &yamlNode{scalar: strconv.FormatBool(t)}, nil
+	case nil:
+		return &yamlNode{scalar: "null"}, nil
+	default:
+		return nil, fmt.Errorf("unexpected json token: %v", tok)
+	}
+}
+
+// isInline reports whether n is written on the same line as its key.
+func (n *yamlNode) isInline() bool {
+	return !(n.isMap || n.isSeq) || len(n.children) == 0
+}
+
+func (n *yamlNode) inline() string {
+	switch {
+	case n.isMap:
+		return "{}"
+	case n.isSeq:
+		return "[]"
+	default:
+		return n.scalar
+	}
+}
+
+func (n *yamlNode) emit(b *strings.Builder, indent int) {
+	prefix := strings.Repeat(" ", indent)
+
+	for i, child := range n.children {
+		if n.isMap {
+			b.WriteString(prefix)
+			b.WriteString(yamlString(n.keys[i]))
+			b.WriteByte(':')
+			if child.isInline() {
+				b.WriteByte(' ')
+				b.WriteString(child.inline())
+				b.WriteByte('\n')
+			} else {
+				b.WriteByte('\n')
+				child.emit(b, indent+2)
+			}
+			continue
+		}
+
+		if child.isInline() {
+			b.WriteString(prefix)
+			b.WriteString("- ")
+			b.WriteString(child.inline())
+			b.WriteByte('\n')
+			continue
+		}
+
+		// Emit the child indented and replace the first line's indentation
+		// with the sequence item indicator.
+		var cb strings.Builder
+		child.emit(&cb, indent+2)
+		b.WriteString(prefix)
+		b.WriteString("- ")
+		b.WriteString(cb.String()[indent+2:])
+	}
+}
+
+var plainYAMLString = regexp.MustCompile(`^[A-Za-z_/$][A-Za-z0-9_./{}#$+-]*$`)
+
+// yamlString renders s as a YAML scalar. s is emitted as a plain scalar if
+// that is unambiguous and as a double quoted scalar otherwise.
+func yamlString(s string) string {
+	if plainYAMLString.MatchString(s) {
+		switch strings.ToLower(s) {
+		case "true", "false", "null", "yes", "no", "on", "off", "y", "n":
+		default:
+			return s
+		}
+	}
+
+	// JSON string escapes are valid within YAML double quoted scalars.
+	data, _ := json.Marshal(s)
+	return string(data)
+}
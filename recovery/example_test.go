@@ -0,0 +1,14 @@
+package recovery_test
+
+import (
+	"net/http"
+
+	"github.com/halimath/httputils/recovery"
+)
+
+func Example() {
+	// restAPI is a http.Handler that defines some kind of resource.
+	restAPI := http.NewServeMux()
+
+	http.ListenAndServe(":1234", recovery.Middleware()(restAPI))
+}
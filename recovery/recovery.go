@@ -0,0 +1,161 @@
+// Package recovery provides a HTTP middleware that recovers from panics raised
+// by downstream handlers and converts them into error responses.
+package recovery
+
+import (
+	"fmt"
+	"net/http"
+	"runtime"
+
+	"github.com/halimath/httputils"
+	"github.com/halimath/httputils/response"
+	"github.com/halimath/kvlog"
+)
+
+// PanicError is an error created from a recovered panic. It captures the value
+// passed to panic as well as the stack trace of the panicking goroutine.
+type PanicError struct {
+	// The value passed to panic.
+	Value any
+
+	stack []uintptr
+}
+
+// NewPanicError creates a new PanicError from v. NewPanicError must be called
+// from the deferred function that recovered v in order to capture the correct
+// stack trace.
+func NewPanicError(v any) *PanicError {
+	pc := make([]uintptr, 256)
+	// Skip runtime.Callers, NewPanicError and the deferred function.
+	n := runtime.Callers(3, pc)
+
+	return &PanicError{
+		Value: v,
+		stack: pc[:n],
+	}
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Unwrap returns the panic's value if it is an error or nil otherwise.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// StackTrace returns the program counters of the panicking goroutine's stack
+// captured when e has been created. It satisfies [response.StackTracer].
+func (e *PanicError) StackTrace() []uintptr {
+	return e.stack
+}
+
+// Stack returns a formatted version of e's stack trace.
+func (e *PanicError) Stack() string {
+	return response.FormatStackTrace(e.stack)
+}
+
+// --
+
+// ErrorHandler defines a function type for handling recovered panics.
+type ErrorHandler func(http.ResponseWriter, *http.Request, error)
+
+type middleware struct {
+	errorHandler ErrorHandler
+}
+
+// Option defines a mutator type to configure a middleware.
+type Option func(*middleware)
+
+// WithErrorHandler is an [Option] that configures the handler used to send a
+// response for a recovered panic. By default, [response.Error] is used.
+func WithErrorHandler(h ErrorHandler) Option {
+	return func(m *middleware) {
+		m.errorHandler = h
+	}
+}
+
+// Middleware creates a HTTP middleware that recovers from panics raised by
+// downstream handlers. Every recovered panic is logged using the
+// [kvlog.Logger] found in the request's context and converted to a
+// [PanicError] which is passed to the error handler to send a response.
+//
+// Panics with [http.ErrAbortHandler] are not recovered. If the downstream
+// handler has already written the response' header, no error response can be
+// sent; in this case the middleware panics with [http.ErrAbortHandler] after
+// logging to abort the response.
+func Middleware(opts ...Option) httputils.Middleware {
+	mw := &middleware{
+		errorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			response.Error(w, r, err)
+		},
+	}
+
+	for _, opt := range opts {
+		opt(mw)
+	}
+
+	return func(handler http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rw := &responseWriter{ResponseWriter: w}
+
+			defer func() {
+				v := recover()
+				if v == nil {
+					return
+				}
+
+				if v == http.ErrAbortHandler {
+					panic(v)
+				}
+
+				err := NewPanicError(v)
+
+				kvlog.FromContext(r.Context()).Logs("recovered from panic",
+					kvlog.WithKV("method", r.Method),
+					kvlog.WithKV("path", r.URL.Path),
+					kvlog.WithKV("stack", err.Stack()),
+					kvlog.WithErr(err),
+				)
+
+				if rw.wroteHeader {
+					panic(http.ErrAbortHandler)
+				}
+
+				mw.errorHandler(w, r, err)
+			}()
+
+			handler.ServeHTTP(rw, r)
+		})
+	}
+}
+
+// responseWriter wraps a [http.ResponseWriter] and tracks whether the header
+// has been written.
+type responseWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func (w *responseWriter) WriteHeader(statusCode int) {
+	w.wroteHeader = true
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *responseWriter) Write(buf []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(buf)
+}
+
+// Flush flushes the underlying [http.ResponseWriter] if it supports flushing.
+func (w *responseWriter) Flush() {
+	w.wroteHeader = true
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap returns the underlying [http.ResponseWriter] to be used with
+// [http.ResponseController].
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,78 @@
+package recovery
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/halimath/expect"
+	"github.com/halimath/expect/is"
+	"github.com/halimath/httputils/requestbuilder"
+)
+
+func TestMiddleware(t *testing.T) {
+	t.Run("noPanic", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		})).ServeHTTP(w, requestbuilder.Get("/").Request())
+
+		expect.That(t, is.EqualTo(w.Result().StatusCode, http.StatusNoContent))
+	})
+
+	t.Run("panic", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic("kaboom")
+		})).ServeHTTP(w, requestbuilder.Get("/").Request())
+
+		expect.That(t, is.EqualTo(w.Result().StatusCode, http.StatusInternalServerError))
+	})
+
+	t.Run("customErrorHandler", func(t *testing.T) {
+		errKaboom := errors.New("kaboom")
+		var got error
+
+		w := httptest.NewRecorder()
+		Middleware(WithErrorHandler(func(w http.ResponseWriter, r *http.Request, err error) {
+			got = err
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic(errKaboom)
+		})).ServeHTTP(w, requestbuilder.Get("/").Request())
+
+		var pe *PanicError
+		expect.That(t,
+			is.EqualTo(w.Result().StatusCode, http.StatusServiceUnavailable),
+			is.Error(got, errKaboom),
+			is.EqualTo(errors.As(got, &pe), true),
+			is.EqualTo(strings.Contains(pe.Stack(), "recovery.TestMiddleware"), true),
+		)
+	})
+
+	t.Run("headerWritten", func(t *testing.T) {
+		defer func() {
+			expect.That(t, is.EqualTo(recover(), any(http.ErrAbortHandler)))
+		}()
+
+		w := httptest.NewRecorder()
+		Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, "hello")
+			panic("kaboom")
+		})).ServeHTTP(w, requestbuilder.Get("/").Request())
+	})
+
+	t.Run("abortHandler", func(t *testing.T) {
+		defer func() {
+			expect.That(t, is.EqualTo(recover(), any(http.ErrAbortHandler)))
+		}()
+
+		w := httptest.NewRecorder()
+		Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic(http.ErrAbortHandler)
+		})).ServeHTTP(w, requestbuilder.Get("/").Request())
+	})
+}
@@ -0,0 +1,16 @@
+package requestid_test
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/halimath/httputils/requestid"
+)
+
+func ExampleNewMiddleware() {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "your request id is %s", requestid.FromRequest(r))
+	})
+
+	http.ListenAndServe(":8080", requestid.NewMiddleware()(handler))
+}
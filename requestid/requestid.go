@@ -0,0 +1,162 @@
+// Package requestid provides a HTTP middleware that assigns a unique ID to
+// every request. The ID can be used to correlate log messages and error
+// responses with a single request.
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/halimath/httputils"
+	"github.com/halimath/kvlog"
+)
+
+// DefaultHeader defines the default name of the request and response header
+// carrying the request ID.
+const DefaultHeader = "X-Request-Id"
+
+// maxLength defines the maximum length of request IDs accepted from clients.
+const maxLength = 128
+
+type middleware struct {
+	header        string
+	trustIncoming bool
+	generator     func() string
+}
+
+// Option defines a mutator type to configure a middleware.
+type Option func(*middleware)
+
+// WithHeader is an [Option] that configures the name of the header carrying
+// the request ID.
+func WithHeader(name string) Option {
+	return func(m *middleware) {
+		m.header = name
+	}
+}
+
+// WithGenerator is an [Option] that configures the function used to generate
+// request IDs. By default, [Generate] is used.
+func WithGenerator(g func() string) Option {
+	return func(m *middleware) {
+		m.generator = g
+	}
+}
+
+// TrustIncoming is an [Option] that causes IDs sent by clients to be reused
+// instead of generating a new one. Incoming IDs are only accepted if they
+// consist of at most 128 ASCII letters, digits and the characters -._:+/= and
+// are replaced with a generated one otherwise. Only use this option if the
+// service is accessed through trusted proxies or other services assigning
+// request IDs.
+func TrustIncoming() Option {
+	return func(m *middleware) {
+		m.trustIncoming = true
+	}
+}
+
+// NewMiddleware creates a HTTP middleware that assigns an ID to every request.
+// By default, a new ID is generated for every request; use [TrustIncoming] to
+// reuse IDs sent in the [DefaultHeader] request header. The ID is sent as a response
+// header, stored in the request's context (see [FromContext]) and added as
+// request_id to the [kvlog.Logger] found in the request's context.
+func NewMiddleware(opts ...Option) httputils.Middleware {
+	mw := &middleware{
+		header:    DefaultHeader,
+		generator: Generate,
+	}
+
+	for _, opt := range opts {
+		opt(mw)
+	}
+
+	return func(handler http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := ""
+			if mw.trustIncoming {
+				id = r.Header.Get(mw.header)
+				if !isValid(id) {
+					id = ""
+				}
+			}
+
+			if id == "" {
+				id = mw.generator()
+			}
+
+			w.Header().Set(mw.header, id)
+
+			ctx := context.WithValue(r.Context(), contextKey, id)
+			ctx = kvlog.ContextWithLogger(ctx, kvlog.FromContext(ctx).Sub(kvlog.WithKV("request_id", id)))
+
+			handler.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// isValid reports whether id is acceptable as a request ID sent by a client.
+// Only ASCII letters, digits and the characters -._:+/= are accepted.
+func isValid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		if (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (c < '0' || c > '9') && !strings.ContainsRune("-._:+/=", rune(c)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// --
+
+// Private type for the context key
+type contextKeyType string
+
+// Sentinel value used as the context key to hold the request ID.
+const contextKey contextKeyType = "requestID"
+
+// FromContext returns the request ID stored in ctx. If ctx contains no ID, the
+// empty string is returned.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey).(string)
+	return id
+}
+
+// FromRequest returns the ID of r. This is equivalent to
+//
+//	FromContext(r.Context())
+func FromRequest(r *http.Request) string {
+	return FromContext(r.Context())
+}
+
+// Generate generates a random request ID using the format of a version 4 UUID.
+func Generate() string {
+	var buf [16]byte
+	if _, err := rand.Read(buf[:]); err != nil {
+		panic(fmt.Sprintf("unable to generate request id: %v", err))
+	}
+
+	buf[6] = (buf[6] & 0x0f) | 0x40 // Version 4
+	buf[8] = (buf[8] & 0x3f) | 0x80 // Variant RFC 4122
+
+	var s [36]byte
+	hex.Encode(s[0:8], buf[0:4])
+	s[8] = '-'
+	hex.Encode(s[9:13], buf[4:6])
+	s[13] = '-'
+	hex.Encode(s[14:18], buf[6:8])
+	s[18] = '-'
+	hex.Encode(s[19:23], buf[8:10])
+	s[23] = '-'
+	hex.Encode(s[24:], buf[10:])
+
+	return string(s[:])
+}
@@ -0,0 +1,68 @@
+package requestid
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/halimath/expect"
+	"github.com/halimath/expect/is"
+	"github.com/halimath/httputils/requestbuilder"
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestNewMiddleware(t *testing.T) {
+	var got string
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = FromRequest(r)
+	})
+
+	t.Run("generate", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		NewMiddleware()(h).ServeHTTP(w, requestbuilder.Get("/").Request())
+
+		expect.That(t,
+			is.EqualTo(uuidPattern.MatchString(got), true),
+			is.EqualTo(w.Header().Get(DefaultHeader), got),
+		)
+	})
+
+	t.Run("incomingIgnoredByDefault", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		NewMiddleware()(h).ServeHTTP(w, requestbuilder.Get("/").AddHeader("X-Request-Id", "abc-123").Request())
+
+		expect.That(t,
+			is.EqualTo(uuidPattern.MatchString(got), true),
+			is.EqualTo(w.Header().Get(DefaultHeader), got),
+		)
+	})
+
+	t.Run("TrustIncoming", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		NewMiddleware(TrustIncoming(), WithHeader("X-Correlation-Id"))(h).
+			ServeHTTP(w, requestbuilder.Get("/").AddHeader("X-Correlation-Id", "abc-123").Request())
+
+		expect.That(t,
+			is.EqualTo(got, "abc-123"),
+			is.EqualTo(w.Header().Get("X-Correlation-Id"), "abc-123"),
+		)
+	})
+
+	for name, id := range map[string]string{
+		"tooLong":    strings.Repeat("a", 129),
+		"whitespace": "abc 123",
+		"quotes":     `abc"123`,
+		"markup":     "<script>",
+	} {
+		t.Run("invalidIncoming/"+name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			NewMiddleware(TrustIncoming(), WithGenerator(func() string { return "generated" }))(h).
+				ServeHTTP(w, requestbuilder.Get("/").AddHeader("X-Request-Id", id).Request())
+
+			expect.That(t, is.EqualTo(got, "generated"))
+		})
+	}
+}
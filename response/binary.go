@@ -0,0 +1,312 @@
+package response
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// binaryNode is an order preserving representation of a JSON value used as
+// the intermediate format to encode CBOR and MessagePack. Using JSON as the
+// intermediate format makes both encoders honor json struct tags and custom
+// [json.Marshaler] implementations.
+type binaryNode struct {
+	// keys contains the keys of a map node.
+	keys []string
+
+	// children contains the values of a map or array node.
+	children []*binaryNode
+
+	// isMap and isArray determine the node's kind. Nodes that are neither are
+	// scalars.
+	isMap, isArray bool
+
+	// scalar contains the value of a scalar node: nil, bool, string,
+	// int64, uint64 or float64.
+	scalar any
+}
+
+// toBinaryNode marshals payload to JSON and parses the result into a
+// binaryNode.
+func toBinaryNode(payload any) (*binaryNode, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	return parseBinaryNode(dec)
+}
+
+func parseBinaryNode(dec *json.Decoder) (*binaryNode, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := tok.(type) {
+	case json.Delim:
+		n := &binaryNode{isMap: t == '{', isArray: t == '['}
+		for dec.More() {
+			if n.isMap {
+				k, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				n.keys = append(n.keys, k.(string))
+			}
+
+			child, err := parseBinaryNode(dec)
+			if err != nil {
+				return nil, err
+			}
+			n.children = append(n.children, child)
+		}
+
+		// Consume the closing delimiter
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		return n, nil
+
+	case json.Number:
+		return &binaryNode{scalar: parseNumber(t)}, nil
+
+	case string, bool, nil:
+		return &binaryNode{scalar: t}, nil
+
+	default:
+		return nil, fmt.Errorf("unexpected json token: %v", tok)
+	}
+}
+
+// parseNumber converts n to an int64 or uint64 if n is an integer that fits
+// into one of these types and to a float64 otherwise.
+func parseNumber(n json.Number) any {
+	s := n.String()
+	if !strings.ContainsAny(s, ".eE") {
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return i
+		}
+		if u, err := strconv.ParseUint(s, 10, 64); err == nil {
+			return u
+		}
+	}
+
+	f, _ := n.Float64()
+	return f
+}
+
+// --
+
+// EncodeCBOR encodes payload using the Concise Binary Object Representation
+// (CBOR) as defined in [RFC8949]. payload is converted to its JSON data model
+// first, so json struct tags apply. Integers are encoded as CBOR integers,
+// all other numbers as double precision floats.
+//
+// [RFC8949]: https://www.rfc-editor.org/rfc/rfc8949
+func EncodeCBOR(_ *http.Request, payload any) ([]byte, error) {
+	n, err := toBinaryNode(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return n.appendCBOR(nil), nil
+}
+
+// CBOR major types
+const (
+	cborUnsignedInt = 0
+	cborNegativeInt = 1
+	cborTextString  = 3
+	cborArray       = 4
+	cborMap         = 5
+	cborSimple      = 7
+)
+
+func (n *binaryNode) appendCBOR(buf []byte) []byte {
+	switch {
+	case n.isArray:
+		buf = appendCBORHead(buf, cborArray, uint64(len(n.children)))
+		for _, c := range n.children {
+			buf = c.appendCBOR(buf)
+		}
+		return buf
+
+	case n.isMap:
+		buf = appendCBORHead(buf, cborMap, uint64(len(n.children)))
+		for i, c := range n.children {
+			buf = appendCBORHead(buf, cborTextString, uint64(len(n.keys[i])))
+			buf = append(buf, n.keys[i]...)
+			buf = c.appendCBOR(buf)
+		}
+		return buf
+	}
+
+	switch v := n.scalar.(type) {
+	case nil:
+		return append(buf, cborSimple<<5|22)
+	case bool:
+		if v {
+			return append(buf, cborSimple<<5|21)
+		}
+		return append(buf, cborSimple<<5|20)
+	case string:
+		buf = appendCBORHead(buf, cborTextString, uint64(len(v)))
+		return append(buf, v...)
+	case int64:
+		if v < 0 {
+			return appendCBORHead(buf, cborNegativeInt, uint64(-(v + 1)))
+		}
+		return appendCBORHead(buf, cborUnsignedInt, uint64(v))
+	case uint64:
+		return appendCBORHead(buf, cborUnsignedInt, v)
+	default:
+		buf = append(buf, cborSimple<<5|27)
+		return binary.BigEndian.AppendUint64(buf, math.Float64bits(v.(float64)))
+	}
+}
+
+// appendCBORHead appends the initial byte(s) of a data item of major type
+// major with argument arg using the shortest possible encoding.
+func appendCBORHead(buf []byte, major byte, arg uint64) []byte {
+	major <<= 5
+
+	switch {
+	case arg < 24:
+		return append(buf, major|byte(arg))
+	case arg <= math.MaxUint8:
+		return append(buf, major|24, byte(arg))
+	case arg <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(buf, major|25), uint16(arg))
+	case arg <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(buf, major|26), uint32(arg))
+	default:
+		return binary.BigEndian.AppendUint64(append(buf, major|27), arg)
+	}
+}
+
+// --
+
+// EncodeMessagePack encodes payload using the [MessagePack] format. payload
+// is converted to its JSON data model first, so json struct tags apply.
+// Integers are encoded using the smallest integer format, all other numbers as
+// float 64.
+//
+// [MessagePack]: https://github.com/msgpack/msgpack/blob/master/spec.md
+func EncodeMessagePack(_ *http.Request, payload any) ([]byte, error) {
+	n, err := toBinaryNode(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return n.appendMessagePack(nil), nil
+}
+
+func (n *binaryNode) appendMessagePack(buf []byte) []byte {
+	switch {
+	case n.isArray:
+		buf = appendMessagePackLength(buf, len(n.children), 0x90, 0xdc)
+		for _, c := range n.children {
+			buf = c.appendMessagePack(buf)
+		}
+		return buf
+
+	case n.isMap:
+		buf = appendMessagePackLength(buf, len(n.children), 0x80, 0xde)
+		for i, c := range n.children {
+			buf = appendMessagePackString(buf, n.keys[i])
+			buf = c.appendMessagePack(buf)
+		}
+		return buf
+	}
+
+	switch v := n.scalar.(type) {
+	case nil:
+		return append(buf, 0xc0)
+	case bool:
+		if v {
+			return append(buf, 0xc3)
+		}
+		return append(buf, 0xc2)
+	case string:
+		return appendMessagePackString(buf, v)
+	case int64:
+		if v >= 0 {
+			return appendMessagePackUint(buf, uint64(v))
+		}
+		return appendMessagePackInt(buf, v)
+	case uint64:
+		return appendMessagePackUint(buf, v)
+	default:
+		return binary.BigEndian.AppendUint64(append(buf, 0xcb), math.Float64bits(v.(float64)))
+	}
+}
+
+// appendMessagePackLength appends the header of an array or map with l
+// elements. fix defines the format byte of the fix format, format16 the one
+// of the 16 bit format; the 32 bit format always follows the 16 bit format.
+func appendMessagePackLength(buf []byte, l int, fix, format16 byte) []byte {
+	switch {
+	case l < 16:
+		return append(buf, fix|byte(l))
+	case l <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(buf, format16), uint16(l))
+	default:
+		return binary.BigEndian.AppendUint32(append(buf, format16+1), uint32(l))
+	}
+}
+
+func appendMessagePackString(buf []byte, s string) []byte {
+	l := len(s)
+
+	switch {
+	case l < 32:
+		buf = append(buf, 0xa0|byte(l))
+	case l <= math.MaxUint8:
+		buf = append(buf, 0xd9, byte(l))
+	case l <= math.MaxUint16:
+		buf = binary.BigEndian.AppendUint16(append(buf, 0xda), uint16(l))
+	default:
+		buf = binary.BigEndian.AppendUint32(append(buf, 0xdb), uint32(l))
+	}
+
+	return append(buf, s...)
+}
+
+func appendMessagePackUint(buf []byte, v uint64) []byte {
+	switch {
+	case v <= 0x7f:
+		return append(buf, byte(v))
+	case v <= math.MaxUint8:
+		return append(buf, 0xcc, byte(v))
+	case v <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(buf, 0xcd), uint16(v))
+	case v <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(buf, 0xce), uint32(v))
+	default:
+		return binary.BigEndian.AppendUint64(append(buf, 0xcf), v)
+	}
+}
+
+func appendMessagePackInt(buf []byte, v int64) []byte {
+	switch {
+	case v >= -32:
+		return append(buf, byte(v))
+	case v >= math.MinInt8:
+		return append(buf, 0xd0, byte(v))
+	case v >= math.MinInt16:
+		return binary.BigEndian.AppendUint16(append(buf, 0xd1), uint16(v))
+	case v >= math.MinInt32:
+		return binary.BigEndian.AppendUint32(append(buf, 0xd2), uint32(v))
+	default:
+		return binary.BigEndian.AppendUint64(append(buf, 0xd3), uint64(v))
+	}
+}
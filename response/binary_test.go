@@ -0,0 +1,55 @@
+package response
+
+import (
+	"testing"
+
+	"github.com/halimath/expect"
+	"github.com/halimath/expect/is"
+)
+
+type binaryTestPayload struct {
+	A int     `json:"a"`
+	B []any   `json:"b"`
+	C float64 `json:"c"`
+	D int     `json:"d"`
+	E int     `json:"e"`
+	F string  `json:"f,omitempty"`
+}
+
+var binaryTestValue = binaryTestPayload{
+	A: 1,
+	B: []any{true, nil, -1, "x"},
+	C: 1.5,
+	D: 1000,
+	E: -500,
+}
+
+func TestEncodeCBOR(t *testing.T) {
+	got, err := EncodeCBOR(nil, binaryTestValue)
+	expect.That(t,
+		is.NoError(err),
+		is.DeepEqualTo(got, []byte{
+			0xa5,
+			0x61, 'a', 0x01,
+			0x61, 'b', 0x84, 0xf5, 0xf6, 0x20, 0x61, 'x',
+			0x61, 'c', 0xfb, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0,
+			0x61, 'd', 0x19, 0x03, 0xe8,
+			0x61, 'e', 0x39, 0x01, 0xf3,
+		}),
+	)
+}
+
+func TestEncodeMessagePack(t *testing.T) {
+	got, err := EncodeMessagePack(nil, binaryTestValue)
+	expect.That(t,
+		is.NoError(err),
+		is.DeepEqualTo(got, []byte{
+			0x85,
+			0xa1, 'a', 0x01,
+			0xa1, 'b', 0x94, 0xc3, 0xc0, 0xff, 0xa1, 'x',
+			0xa1, 'c', 0xcb, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0,
+			0xa1, 'd', 0xcd, 0x03, 0xe8,
+			0xa1, 'e', 0xd1, 0xfe, 0x0c,
+		}),
+	)
+}
@@ -0,0 +1,214 @@
+package response
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cacheFlags records the directives set on a [CacheControl].
+type cacheFlags uint16
+
+const (
+	ccPublic cacheFlags = 1 << iota
+	ccPrivate
+	ccNoCache
+	ccNoStore
+	ccNoTransform
+	ccMustRevalidate
+	ccProxyRevalidate
+	ccImmutable
+	ccMaxAge
+	ccSMaxAge
+	ccStaleWhileRevalidate
+	ccStaleIfError
+)
+
+// CacheControl builds the value of a Cache-Control response header as defined
+// in [RFC9111] and its extensions [RFC5861] and [RFC8246]. The zero value
+// contains no directives. Methods return a modified copy so a CacheControl can
+// be used as a template for multiple responses, i.e.
+//
+//	static := response.CacheControl{}.Public().MaxAge(365 * 24 * time.Hour).Immutable()
+//
+// Durations are sent in whole seconds; negative durations are sent as 0.
+//
+// [RFC9111]: https://www.rfc-editor.org/rfc/rfc9111#section-5.2.2
+// [RFC5861]: https://www.rfc-editor.org/rfc/rfc5861
+// [RFC8246]: https://www.rfc-editor.org/rfc/rfc8246
+type CacheControl struct {
+	flags                cacheFlags
+	maxAge               time.Duration
+	sMaxAge              time.Duration
+	staleWhileRevalidate time.Duration
+	staleIfError         time.Duration
+}
+
+// Public adds the public directive allowing shared caches to store the
+// response even if it would otherwise not be cacheable (i.e. because the
+// request carried an Authorization header).
+func (c CacheControl) Public() CacheControl { return c.with(ccPublic) }
+
+// Private adds the private directive preventing shared caches from storing the
+// response.
+func (c CacheControl) Private() CacheControl { return c.with(ccPrivate) }
+
+// NoCache adds the no-cache directive requiring caches to revalidate the
+// response before every use.
+func (c CacheControl) NoCache() CacheControl { return c.with(ccNoCache) }
+
+// NoStore adds the no-store directive preventing any cache from storing the
+// response.
+func (c CacheControl) NoStore() CacheControl { return c.with(ccNoStore) }
+
+// NoTransform adds the no-transform directive preventing intermediaries from
+// transforming the response's content.
+func (c CacheControl) NoTransform() CacheControl { return c.with(ccNoTransform) }
+
+// MustRevalidate adds the must-revalidate directive preventing caches from
+// using the response once it has become stale without revalidation.
+func (c CacheControl) MustRevalidate() CacheControl { return c.with(ccMustRevalidate) }
+
+// ProxyRevalidate adds the proxy-revalidate directive which is the same as
+// must-revalidate but only applies to shared caches.
+func (c CacheControl) ProxyRevalidate() CacheControl { return c.with(ccProxyRevalidate) }
+
+// Immutable adds the immutable directive signaling that the response will not
+// change while it is fresh, so clients need not revalidate it (i.e. on
+// reload). Use this for versioned static assets.
+func (c CacheControl) Immutable() CacheControl { return c.with(ccImmutable) }
+
+// MaxAge adds the max-age directive defining how long the response is fresh.
+func (c CacheControl) MaxAge(d time.Duration) CacheControl {
+	c.maxAge = d
+	return c.with(ccMaxAge)
+}
+
+// SMaxAge adds the s-maxage directive which overrides max-age for shared
+// caches.
+func (c CacheControl) SMaxAge(d time.Duration) CacheControl {
+	c.sMaxAge = d
+	return c.with(ccSMaxAge)
+}
+
+// StaleWhileRevalidate adds the stale-while-revalidate directive allowing
+// caches to serve the stale response for d while revalidating it in the
+// background.
+func (c CacheControl) StaleWhileRevalidate(d time.Duration) CacheControl {
+	c.staleWhileRevalidate = d
+	return c.with(ccStaleWhileRevalidate)
+}
+
+// StaleIfError adds the stale-if-error directive allowing caches to serve the
+// stale response for d if revalidation fails with an error.
+func (c CacheControl) StaleIfError(d time.Duration) CacheControl {
+	c.staleIfError = d
+	return c.with(ccStaleIfError)
+}
+
+// IsZero reports whether c contains no directives.
+func (c CacheControl) IsZero() bool { return c.flags == 0 }
+
+// String returns c formatted as a Cache-Control header value.
+func (c CacheControl) String() string {
+	var directives []string
+
+	for _, d := range []struct {
+		flag cacheFlags
+		name string
+	}{
+		{ccPublic, "public"},
+		{ccPrivate, "private"},
+		{ccNoCache, "no-cache"},
+		{ccNoStore, "no-store"},
+		{ccNoTransform, "no-transform"},
+	} {
+		if c.flags&d.flag != 0 {
+			directives = append(directives, d.name)
+		}
+	}
+
+	for _, d := range []struct {
+		flag  cacheFlags
+		name  string
+		value time.Duration
+	}{
+		{ccMaxAge, "max-age", c.maxAge},
+		{ccSMaxAge, "s-maxage", c.sMaxAge},
+		{ccStaleWhileRevalidate, "stale-while-revalidate", c.staleWhileRevalidate},
+		{ccStaleIfError, "stale-if-error", c.staleIfError},
+	} {
+		if c.flags&d.flag != 0 {
+			directives = append(directives, d.name+"="+strconv.FormatInt(seconds(d.value), 10))
+		}
+	}
+
+	for _, d := range []struct {
+		flag cacheFlags
+		name string
+	}{
+		{ccMustRevalidate, "must-revalidate"},
+		{ccProxyRevalidate, "proxy-revalidate"},
+		{ccImmutable, "immutable"},
+	} {
+		if c.flags&d.flag != 0 {
+			directives = append(directives, d.name)
+		}
+	}
+
+	return strings.Join(directives, ", ")
+}
+
+func (c CacheControl) with(f cacheFlags) CacheControl {
+	c.flags |= f
+	return c
+}
+
+// seconds returns d in whole seconds with negative values reduced to 0.
+func seconds(d time.Duration) int64 {
+	return max(int64(d/time.Second), 0)
+}
+
+// Cache is an Option that sets the Cache-Control header to cc. For HTTP/1.0
+// caches, which do not understand Cache-Control, Cache also sets the Expires
+// header: if cc contains max-age, Expires is set to the time the response
+// becomes stale; if cc contains no-store or no-cache, Expires is set to a
+// date in the past. If the response already carries an Age header (see
+// [Age]), it is subtracted from max-age when computing Expires, so apply Age
+// before Cache.
+func Cache(cc CacheControl) Option {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		h := w.Header()
+		h.Set("Cache-Control", cc.String())
+
+		switch {
+		case cc.flags&(ccNoStore|ccNoCache) != 0:
+			h.Set("Expires", time.Unix(0, 0).UTC().Format(http.TimeFormat))
+
+		case cc.flags&ccMaxAge != 0:
+			lifetime := time.Duration(seconds(cc.maxAge)) * time.Second
+			if age, err := strconv.ParseInt(h.Get("Age"), 10, 64); err == nil && age > 0 {
+				lifetime -= time.Duration(age) * time.Second
+			}
+			h.Set("Expires", time.Now().Add(lifetime).UTC().Format(http.TimeFormat))
+		}
+
+		return nil
+	}
+}
+
+// Expires is an Option that sets the Expires header to t. Prefer [Cache] with
+// max-age, which takes precedence over Expires for caches that understand
+// Cache-Control.
+func Expires(t time.Time) Option {
+	return SetHeader("Expires", t.UTC().Format(http.TimeFormat), true)
+}
+
+// Age is an Option that sets the Age header to d in whole seconds. Use Age
+// when sending a representation that has been taken from a cache (i.e. a
+// response received from an upstream server) to tell clients how long ago it
+// was generated.
+func Age(d time.Duration) Option {
+	return SetHeader("Age", strconv.FormatInt(seconds(d), 10), true)
+}
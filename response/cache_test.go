@@ -0,0 +1,97 @@
+package response
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/halimath/expect"
+	"github.com/halimath/expect/is"
+)
+
+func TestCacheControl_String(t *testing.T) {
+	tests := map[string]struct {
+		cc   CacheControl
+		want string
+	}{
+		"zero": {CacheControl{}, ""},
+		"static": {
+			CacheControl{}.Immutable().MaxAge(365 * 24 * time.Hour).Public(),
+			"public, max-age=31536000, immutable",
+		},
+		"shared": {
+			CacheControl{}.MaxAge(time.Minute).SMaxAge(10 * time.Minute).StaleWhileRevalidate(30 * time.Second).StaleIfError(time.Hour),
+			"max-age=60, s-maxage=600, stale-while-revalidate=30, stale-if-error=3600",
+		},
+		"private": {
+			CacheControl{}.Private().NoCache().MustRevalidate(),
+			"private, no-cache, must-revalidate",
+		},
+		"max_age_zero": {CacheControl{}.MaxAge(0), "max-age=0"},
+		"negative":     {CacheControl{}.MaxAge(-time.Second), "max-age=0"},
+		"no_store":     {CacheControl{}.NoStore().NoTransform().ProxyRevalidate(), "no-store, no-transform, proxy-revalidate"},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			expect.That(t, is.EqualTo(test.cc.String(), test.want))
+		})
+	}
+}
+
+func TestCacheControl_copy(t *testing.T) {
+	base := CacheControl{}.Public()
+	_ = base.MaxAge(time.Hour)
+
+	expect.That(t,
+		is.EqualTo(base.String(), "public"),
+		is.EqualTo(CacheControl{}.IsZero(), true),
+		is.EqualTo(base.IsZero(), false),
+	)
+}
+
+func TestCache(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	t.Run("max_age", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		Send(w, r, Cache(CacheControl{}.Public().MaxAge(time.Hour)))
+
+		expires, err := http.ParseTime(w.Header().Get("Expires"))
+		expect.That(t,
+			is.EqualTo(w.Header().Get("Cache-Control"), "public, max-age=3600"),
+			is.NoError(err),
+		)
+		expect.That(t, is.EqualTo(expires.After(time.Now().Add(59*time.Minute)), true))
+	})
+
+	t.Run("age", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		Send(w, r, Age(30*time.Minute), Cache(CacheControl{}.MaxAge(time.Hour)))
+
+		expires, err := http.ParseTime(w.Header().Get("Expires"))
+		expect.That(t,
+			is.EqualTo(w.Header().Get("Age"), "1800"),
+			is.NoError(err),
+		)
+		expect.That(t, is.EqualTo(expires.Before(time.Now().Add(31*time.Minute)), true))
+	})
+
+	t.Run("no_store", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		Send(w, r, Cache(CacheControl{}.NoStore()))
+
+		expect.That(t,
+			is.EqualTo(w.Header().Get("Cache-Control"), "no-store"),
+			is.EqualTo(w.Header().Get("Expires"), "Thu, 01 Jan 1970 00:00:00 GMT"),
+		)
+	})
+
+	t.Run("expires", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		Send(w, r, Expires(time.Date(2024, 5, 1, 12, 0, 0, 0, time.FixedZone("CEST", 7200))))
+
+		expect.That(t, is.EqualTo(w.Header().Get("Expires"), "Wed, 01 May 2024 10:00:00 GMT"))
+	})
+}
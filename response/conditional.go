@@ -0,0 +1,163 @@
+package response
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ErrPreconditionFailed is returned from [GuardWrite] if the request's
+// preconditions do not match the current state of the resource.
+var ErrPreconditionFailed = errors.New("precondition failed")
+
+// ErrPreconditionRequired is returned from [GuardWrite] if the request
+// contains no precondition.
+var ErrPreconditionRequired = errors.New("precondition required")
+
+// ComputeETag computes an entity tag from data using a truncated SHA-256
+// hash. If weak is true, a weak entity tag is returned. The result is
+// formatted for use as an ETag header value.
+func ComputeETag(data []byte, weak bool) string {
+	sum := sha256.Sum256(data)
+	tag := `"` + base64.RawURLEncoding.EncodeToString(sum[:16]) + `"`
+	if weak {
+		return "W/" + tag
+	}
+	return tag
+}
+
+// ETag is an Option that sets the ETag header to etag. Use ETag to provide a
+// precomputed validator, i.e. derived from a version number. If etag is not
+// enclosed in double quotes (and not a weak entity tag), quotes are added.
+func ETag(etag string) Option {
+	if !strings.HasPrefix(etag, `"`) && !strings.HasPrefix(etag, `W/"`) {
+		etag = `"` + etag + `"`
+	}
+	return SetHeader("ETag", etag, true)
+}
+
+// LastModified is an Option that sets the Last-Modified header to t.
+func LastModified(t time.Time) Option {
+	return SetHeader("Last-Modified", t.UTC().Format(http.TimeFormat), true)
+}
+
+// EvaluatePreconditions evaluates the conditional request headers If-Match,
+// If-Unmodified-Since, If-None-Match and If-Modified-Since of r against the
+// current entity tag etag and modification time lastModified of the selected
+// representation as defined in [RFC9110] section 13.2.2. An empty etag or a
+// zero lastModified denote that no such validator exists. If neither
+// validator exists, the resource is considered to have no current
+// representation, so the wildcard * given with If-Match or If-None-Match does
+// not match.
+//
+// EvaluatePreconditions returns [http.StatusNotModified] or
+// [http.StatusPreconditionFailed] if the request should be answered with the
+// respective status code and 0 if the request should be processed.
+//
+// [RFC9110]: https://www.rfc-editor.org/rfc/rfc9110#section-13.2.2
+func EvaluatePreconditions(r *http.Request, etag string, lastModified time.Time) int {
+	lastModified = lastModified.Truncate(time.Second)
+	safe := r.Method == http.MethodGet || r.Method == http.MethodHead
+	exists := etag != "" || !lastModified.IsZero()
+
+	if im := r.Header.Get("If-Match"); im != "" {
+		if !matchETag(im, etag, exists, false) {
+			return http.StatusPreconditionFailed
+		}
+	} else if ius := r.Header.Get("If-Unmodified-Since"); ius != "" && !lastModified.IsZero() {
+		if t, err := http.ParseTime(ius); err == nil && lastModified.After(t) {
+			return http.StatusPreconditionFailed
+		}
+	}
+
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		if matchETag(inm, etag, exists, true) {
+			if safe {
+				return http.StatusNotModified
+			}
+			return http.StatusPreconditionFailed
+		}
+	} else if ims := r.Header.Get("If-Modified-Since"); ims != "" && safe && !lastModified.IsZero() {
+		if t, err := http.ParseTime(ims); err == nil && !lastModified.After(t) {
+			return http.StatusNotModified
+		}
+	}
+
+	return 0
+}
+
+// GuardWrite guards a state changing request with optimistic concurrency
+// control. etag and lastModified are the validators of the resource's current
+// state. GuardWrite returns [ErrPreconditionRequired] if r carries neither an
+// If-Match nor an If-Unmodified-Since header and [ErrPreconditionFailed] if
+// the preconditions do not match the current state, i.e. because the
+// resource has been modified concurrently.
+func GuardWrite(r *http.Request, etag string, lastModified time.Time) error {
+	if r.Header.Get("If-Match") == "" && r.Header.Get("If-Unmodified-Since") == "" {
+		return ErrPreconditionRequired
+	}
+
+	if EvaluatePreconditions(r, etag, lastModified) != 0 {
+		return ErrPreconditionFailed
+	}
+
+	return nil
+}
+
+// matchETag reports whether the list of entity tags given as header value h
+// (as sent with If-Match or If-None-Match) matches etag. weak selects weak
+// comparison; strong comparison never matches weak entity tags. The wildcard *
+// matches if exists is true, i.e. a current representation exists.
+func matchETag(h, etag string, exists, weak bool) bool {
+	if strings.TrimSpace(h) == "*" {
+		return exists
+	}
+
+	if etag == "" {
+		return false
+	}
+
+	etagWeak, etagOpaque := splitETag(etag)
+	if etagWeak && !weak {
+		return false
+	}
+
+	for {
+		h = strings.TrimLeft(h, " \t,")
+		if h == "" {
+			return false
+		}
+
+		isWeak := strings.HasPrefix(h, "W/")
+		if isWeak {
+			h = h[2:]
+		}
+
+		if !strings.HasPrefix(h, `"`) {
+			return false
+		}
+
+		end := strings.IndexByte(h[1:], '"')
+		if end < 0 {
+			return false
+		}
+
+		opaque := h[:end+2]
+		h = h[end+2:]
+
+		if opaque == etagOpaque && (weak || !isWeak) {
+			return true
+		}
+	}
+}
+
+// splitETag splits etag into its weakness indicator and opaque tag.
+func splitETag(etag string) (bool, string) {
+	if strings.HasPrefix(etag, "W/") {
+		return true, etag[2:]
+	}
+	return false, etag
+}
@@ -0,0 +1,96 @@
+package response
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/halimath/expect"
+	"github.com/halimath/expect/is"
+)
+
+func TestComputeETag(t *testing.T) {
+	strong := ComputeETag([]byte("hello"), false)
+
+	expect.That(t,
+		is.EqualTo(strong, `"LPJNul-wow4m6Dsqxbning"`),
+		is.EqualTo(ComputeETag([]byte("hello"), true), "W/"+strong),
+	)
+}
+
+func TestETag(t *testing.T) {
+	for in, want := range map[string]string{
+		"v1":       `"v1"`,
+		`"v1"`:     `"v1"`,
+		`W/"v1"`:   `W/"v1"`,
+		"W/broken": `"W/broken"`,
+	} {
+		w := httptest.NewRecorder()
+		Send(w, httptest.NewRequest(http.MethodGet, "/", nil), ETag(in))
+		expect.That(t, is.EqualTo(w.Header().Get("ETag"), want))
+	}
+}
+
+func TestEvaluatePreconditions(t *testing.T) {
+	modified := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	before := modified.Add(-time.Hour).Format(http.TimeFormat)
+	after := modified.Add(time.Hour).Format(http.TimeFormat)
+
+	tests := map[string]struct {
+		method string
+		header map[string]string
+		etag   string
+		want   int
+	}{
+		"none":                       {http.MethodGet, nil, `"a"`, 0},
+		"if_match":                   {http.MethodPut, map[string]string{"If-Match": `"x", "a"`}, `"a"`, 0},
+		"if_match_mismatch":          {http.MethodPut, map[string]string{"If-Match": `"b"`}, `"a"`, http.StatusPreconditionFailed},
+		"if_match_weak":              {http.MethodPut, map[string]string{"If-Match": `W/"a"`}, `"a"`, http.StatusPreconditionFailed},
+		"if_match_star":              {http.MethodPut, map[string]string{"If-Match": `*`}, `"a"`, 0},
+		"if_match_star_no_etag":      {http.MethodPut, map[string]string{"If-Match": `*`}, "", 0},
+		"if_unmodified_since":        {http.MethodPut, map[string]string{"If-Unmodified-Since": after}, `"a"`, 0},
+		"if_unmodified_since_failed": {http.MethodPut, map[string]string{"If-Unmodified-Since": before}, `"a"`, http.StatusPreconditionFailed},
+		"if_match_precedence":        {http.MethodPut, map[string]string{"If-Match": `"a"`, "If-Unmodified-Since": before}, `"a"`, 0},
+		"if_none_match":              {http.MethodGet, map[string]string{"If-None-Match": `W/"a"`}, `"a"`, http.StatusNotModified},
+		"if_none_match_mismatch":     {http.MethodGet, map[string]string{"If-None-Match": `"b"`}, `"a"`, 0},
+		"if_none_match_unsafe":       {http.MethodPost, map[string]string{"If-None-Match": `*`}, `"a"`, http.StatusPreconditionFailed},
+		"if_modified_since":          {http.MethodGet, map[string]string{"If-Modified-Since": after}, `"a"`, http.StatusNotModified},
+		"if_modified_since_modified": {http.MethodGet, map[string]string{"If-Modified-Since": before}, `"a"`, 0},
+		"if_none_match_precedence":   {http.MethodGet, map[string]string{"If-None-Match": `"b"`, "If-Modified-Since": after}, `"a"`, 0},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := httptest.NewRequest(test.method, "/", nil)
+			for k, v := range test.header {
+				r.Header.Set(k, v)
+			}
+
+			expect.That(t, is.EqualTo(EvaluatePreconditions(r, test.etag, modified), test.want))
+		})
+	}
+}
+
+func TestGuardWrite(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPut, "/", nil)
+	expect.That(t, is.Error(GuardWrite(r, `"v1"`, time.Time{}), ErrPreconditionRequired))
+
+	r.Header.Set("If-Match", `"v1"`)
+	expect.That(t,
+		is.NoError(GuardWrite(r, `"v1"`, time.Time{})),
+		is.Error(GuardWrite(r, `"v2"`, time.Time{}), ErrPreconditionFailed),
+	)
+}
+
+func TestGuardWrite_wildcardWithLastModifiedOnly(t *testing.T) {
+	lastModified := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	r := httptest.NewRequest(http.MethodPut, "/", nil)
+	r.Header.Set("If-Match", "*")
+
+	expect.That(t,
+		is.NoError(GuardWrite(r, "", lastModified)),
+		is.Error(GuardWrite(r, "", time.Time{}), ErrPreconditionFailed),
+	)
+}
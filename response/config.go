@@ -0,0 +1,137 @@
+package response
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/halimath/httputils"
+)
+
+// Config customizes how responses are created. A Config can be attached to a
+// request's context (see [NewConfigMiddleware] and [ContextWithConfig]) to
+// configure responses per route or tenant. The zero value provides the
+// defaults used in production.
+type Config struct {
+	// PrettyPrint enables indented JSON and XML output.
+	PrettyPrint bool
+
+	// ErrorDetails enables exposing error messages and stack traces in error
+	// responses (see [Error]). Only enable this for development.
+	ErrorDetails bool
+
+	// DisableHTMLEscaping disables escaping of <, > and & in JSON strings (see
+	// [json.Encoder.SetEscapeHTML]).
+	DisableHTMLEscaping bool
+
+	// ReloadTemplates causes HTML templates to be reloaded on every render
+	// (see [HTMLRenderer]).
+	ReloadTemplates bool
+
+	// JSONContentType defines the content-type used by [JSON]. Defaults to
+	// application/json.
+	JSONContentType string
+
+	// TextContentType defines the content-type used by [PlainText]. Defaults
+	// to text/plain.
+	TextContentType string
+
+	// HTMLContentType defines the content-type used by [HTML]. Defaults to
+	// text/html; charset=utf-8.
+	HTMLContentType string
+}
+
+// DefaultConfig returns the Config used for requests without a Config
+// attached to their context. All development related settings are enabled if
+// DevMode is set to true.
+func DefaultConfig() Config {
+	return Config{
+		PrettyPrint:     DevMode,
+		ErrorDetails:    DevMode,
+		ReloadTemplates: DevMode,
+	}
+}
+
+func (c Config) jsonContentType() string {
+	if c.JSONContentType == "" {
+		return "application/json"
+	}
+	return c.JSONContentType
+}
+
+func (c Config) textContentType() string {
+	if c.TextContentType == "" {
+		return "text/plain"
+	}
+	return c.TextContentType
+}
+
+func (c Config) htmlContentType() string {
+	if c.HTMLContentType == "" {
+		return "text/html; charset=utf-8"
+	}
+	return c.HTMLContentType
+}
+
+// newJSONEncoder creates a [json.Encoder] writing to w configured according
+// to c.
+func (c Config) newJSONEncoder(w *bytes.Buffer) *json.Encoder {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(!c.DisableHTMLEscaping)
+	if c.PrettyPrint {
+		enc.SetIndent("", "  ")
+	}
+	return enc
+}
+
+// marshalJSON marshals payload to JSON according to c.
+func (c Config) marshalJSON(payload any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := c.newJSONEncoder(&buf).Encode(payload); err != nil {
+		return nil, err
+	}
+
+	// Remove the newline added by the encoder
+	return bytes.TrimSuffix(buf.Bytes(), []byte{'\n'}), nil
+}
+
+// --
+
+// Private type for the context key
+type contextKeyType string
+
+// Sentinel value used as the context key to hold the Config.
+const configKey contextKeyType = "config"
+
+// ContextWithConfig returns a new context derived from ctx carrying cfg.
+func ContextWithConfig(ctx context.Context, cfg Config) context.Context {
+	return context.WithValue(ctx, configKey, cfg)
+}
+
+// ConfigFromContext returns the Config stored in ctx. If ctx contains no
+// Config, [DefaultConfig] is returned.
+func ConfigFromContext(ctx context.Context) Config {
+	if cfg, ok := ctx.Value(configKey).(Config); ok {
+		return cfg
+	}
+	return DefaultConfig()
+}
+
+// ConfigFromRequest returns the Config for r. This is equivalent to
+//
+//	ConfigFromContext(r.Context())
+func ConfigFromRequest(r *http.Request) Config {
+	return ConfigFromContext(r.Context())
+}
+
+// NewConfigMiddleware creates a HTTP middleware that attaches cfg to the
+// context of every request. All responses created for these requests use
+// cfg.
+func NewConfigMiddleware(cfg Config) httputils.Middleware {
+	return func(handler http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			handler.ServeHTTP(w, r.WithContext(ContextWithConfig(r.Context(), cfg)))
+		})
+	}
+}
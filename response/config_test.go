@@ -0,0 +1,88 @@
+package response
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/halimath/expect"
+	"github.com/halimath/expect/is"
+)
+
+func TestConfigFromContext(t *testing.T) {
+	t.Run("default", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		expect.That(t, is.DeepEqualTo(ConfigFromRequest(r), Config{}))
+	})
+
+	t.Run("DevMode", func(t *testing.T) {
+		DevMode = true
+		defer func() { DevMode = false }()
+
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		expect.That(t, is.DeepEqualTo(ConfigFromRequest(r), Config{
+			PrettyPrint:     true,
+			ErrorDetails:    true,
+			ReloadTemplates: true,
+		}))
+	})
+}
+
+func TestNewConfigMiddleware(t *testing.T) {
+	cfg := Config{
+		PrettyPrint:         true,
+		ErrorDetails:        true,
+		DisableHTMLEscaping: true,
+		JSONContentType:     "application/vnd.example+json",
+		TextContentType:     "text/plain; charset=utf-8",
+	}
+
+	h := NewConfigMiddleware(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/json":
+			JSON(w, r, map[string]string{"html": "<b>"})
+		case "/text":
+			PlainText(w, r, "hello")
+		default:
+			Error(w, r, errors.New("failed"))
+		}
+	}))
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	t.Run("json", func(t *testing.T) {
+		w := serve("/json")
+		expect.That(t,
+			is.EqualTo(w.Header().Get("Content-Type"), "application/vnd.example+json"),
+			is.EqualTo(w.Body.String(), "{\n  \"html\": \"<b>\"\n}"),
+		)
+	})
+
+	t.Run("text", func(t *testing.T) {
+		w := serve("/text")
+		expect.That(t, is.EqualTo(w.Header().Get("Content-Type"), "text/plain; charset=utf-8"))
+	})
+
+	t.Run("error", func(t *testing.T) {
+		w := serve("/error")
+		expect.That(t,
+			is.EqualTo(w.Code, http.StatusInternalServerError),
+			is.EqualTo(strings.HasPrefix(w.Body.String(), "failed (*errors.errorString)"), true),
+		)
+	})
+
+	t.Run("without_config", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		JSON(w, httptest.NewRequest(http.MethodGet, "/", nil), map[string]string{"html": "<b>"})
+		expect.That(t,
+			is.EqualTo(w.Header().Get("Content-Type"), "application/json"),
+			is.EqualTo(w.Body.String(), `{"html":"\u003cb\u003e"}`),
+		)
+	})
+}
@@ -0,0 +1,183 @@
+package response
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/halimath/httputils/bufferedresponse"
+)
+
+// ErrIsDirectory is returned from [File] when the named file is a directory.
+var ErrIsDirectory = errors.New("is a directory")
+
+// Content sends the data read from content as the response' body. It uses
+// [http.ServeContent] and thus supports
+//
+//   - range requests (Range and If-Range) including multipart/byteranges
+//     responses for multiple ranges
+//   - conditional requests (If-Match, If-None-Match, If-Modified-Since and
+//     If-Unmodified-Since) based on modtime and an ETag header set by opts
+//   - setting content-type based on name's extension or by sniffing the
+//     content if content-type has not been set by opts
+//
+// opts may further customize the response. Headers are applied before
+// serving the content. A status code set by opts replaces the status code 200
+// of a complete response; partial (206) or conditional (304, 412) responses
+// as well as errors (416) retain their status code. Body data written by opts
+// is discarded.
+func Content(w http.ResponseWriter, r *http.Request, name string, modtime time.Time, content io.ReadSeeker, opts ...Option) error {
+	var buf bufferedresponse.ResponseWriter
+	for _, opt := range opts {
+		if err := opt(&buf, r); err != nil {
+			return err
+		}
+	}
+
+	h := w.Header()
+	for k, vals := range buf.Header() {
+		for _, v := range vals {
+			h.Add(k, v)
+		}
+	}
+
+	if buf.StatusCode() != 0 {
+		w = &statusOverrideWriter{ResponseWriter: w, statusCode: buf.StatusCode()}
+	}
+
+	http.ServeContent(w, r, name, modtime, content)
+	return nil
+}
+
+// File sends the file name read from fsys as the response' body. The file's
+// base name and modification time are used as described for [Content]. If the
+// file does not implement [io.Seeker], its content is read into memory first.
+//
+// Errors opening the file are returned, so callers can check for
+// [fs.ErrNotExist] or [fs.ErrPermission]. If name denotes a directory, an error
+// wrapping [ErrIsDirectory] is returned.
+func File(w http.ResponseWriter, r *http.Request, fsys fs.FS, name string, opts ...Option) error {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	if info.IsDir() {
+		return &fs.PathError{Op: "open", Path: name, Err: ErrIsDirectory}
+	}
+
+	content, ok := f.(io.ReadSeeker)
+	if !ok {
+		data, err := io.ReadAll(f)
+		if err != nil {
+			return err
+		}
+		content = bytes.NewReader(data)
+	}
+
+	return Content(w, r, path.Base(name), info.ModTime(), content, opts...)
+}
+
+// statusOverrideWriter replaces a status code 200 with a custom status code.
+type statusOverrideWriter struct {
+	http.ResponseWriter
+	statusCode int
+}
+
+func (w *statusOverrideWriter) WriteHeader(statusCode int) {
+	if statusCode == http.StatusOK {
+		statusCode = w.statusCode
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Unwrap returns the wrapped ResponseWriter to support
+// [http.ResponseController].
+func (w *statusOverrideWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// --
+
+// Attachment is an Option that sets the content-disposition header to
+// attachment causing clients to download the response' body using filename.
+// See [ContentDisposition] for details on how filename is encoded.
+func Attachment(filename string) Option {
+	return SetHeader("Content-Disposition", ContentDisposition("attachment", filename), true)
+}
+
+// Inline is an Option that sets the content-disposition header to inline
+// suggesting filename if the user decides to save the response' body. See
+// [ContentDisposition] for details on how filename is encoded.
+func Inline(filename string) Option {
+	return SetHeader("Content-Disposition", ContentDisposition("inline", filename), true)
+}
+
+// ContentDisposition formats a content-disposition header value as defined in
+// [RFC6266] using disposition type typ. If filename is not empty, it is added
+// as a filename parameter. Filenames that contain characters other than
+// printable ASCII are added as an additional filename* parameter using UTF-8
+// encoding as defined in [RFC8187] with an ASCII fallback in the filename
+// parameter.
+//
+// [RFC6266]: https://www.rfc-editor.org/rfc/rfc6266
+// [RFC8187]: https://www.rfc-editor.org/rfc/rfc8187
+func ContentDisposition(typ, filename string) string {
+	if filename == "" {
+		return typ
+	}
+
+	var b strings.Builder
+	b.WriteString(typ)
+	b.WriteString(`; filename="`)
+
+	ascii := true
+	for _, c := range filename {
+		switch {
+		case c == '"' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(c)
+		case c < ' ' || c > '~':
+			ascii = false
+			b.WriteByte('_')
+		default:
+			b.WriteRune(c)
+		}
+	}
+	b.WriteByte('"')
+
+	if !ascii {
+		b.WriteString("; filename*=UTF-8''")
+		for _, c := range []byte(filename) {
+			if isAttrChar(c) {
+				b.WriteByte(c)
+			} else {
+				b.WriteByte('%')
+				b.WriteByte(upperhex[c>>4])
+				b.WriteByte(upperhex[c&0xf])
+			}
+		}
+	}
+
+	return b.String()
+}
+
+const upperhex = "0123456789ABCDEF"
+
+// isAttrChar reports whether c is an attr-char as defined in RFC 8187 section
+// 3.2.1.
+func isAttrChar(c byte) bool {
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9') ||
+		strings.IndexByte("!#$&+-.^_`|~", c) >= 0
+}
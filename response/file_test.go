@@ -0,0 +1,170 @@
+package response
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/halimath/expect"
+	"github.com/halimath/expect/is"
+)
+
+var fileModTime = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+func TestContent(t *testing.T) {
+	serve := func(r *http.Request, opts ...Option) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		err := Content(w, r, "data.txt", fileModTime, strings.NewReader("0123456789"), opts...)
+		expect.That(t, expect.FailNow(is.NoError(err)))
+		return w
+	}
+
+	t.Run("full", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		w := serve(r, AddHeader("X-Foo", "bar"), StatusCode(http.StatusCreated))
+
+		expect.That(t,
+			is.EqualTo(w.Code, http.StatusCreated),
+			is.EqualTo(w.Header().Get("Content-Type"), "text/plain; charset=utf-8"),
+			is.EqualTo(w.Header().Get("Accept-Ranges"), "bytes"),
+			is.EqualTo(w.Header().Get("X-Foo"), "bar"),
+			is.EqualTo(w.Body.String(), "0123456789"),
+		)
+	})
+
+	t.Run("range", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Range", "bytes=2-4")
+		w := serve(r, StatusCode(http.StatusCreated))
+
+		expect.That(t,
+			is.EqualTo(w.Code, http.StatusPartialContent),
+			is.EqualTo(w.Header().Get("Content-Range"), "bytes 2-4/10"),
+			is.EqualTo(w.Body.String(), "234"),
+		)
+	})
+
+	t.Run("multiple_ranges", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Range", "bytes=0-1,8-")
+		w := serve(r)
+
+		mt, params, err := mime.ParseMediaType(w.Header().Get("Content-Type"))
+		expect.That(t,
+			expect.FailNow(is.NoError(err)),
+			is.EqualTo(w.Code, http.StatusPartialContent),
+			is.EqualTo(mt, "multipart/byteranges"),
+		)
+
+		var parts []string
+		mr := multipart.NewReader(w.Body, params["boundary"])
+		for {
+			p, err := mr.NextPart()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			expect.That(t, expect.FailNow(is.NoError(err)))
+			data, _ := io.ReadAll(p)
+			parts = append(parts, p.Header.Get("Content-Range")+" "+string(data))
+		}
+
+		expect.That(t, is.DeepEqualTo(parts, []string{"bytes 0-1/10 01", "bytes 8-9/10 89"}))
+	})
+
+	t.Run("if_range_mismatch", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Range", "bytes=2-4")
+		r.Header.Set("If-Range", `"v1"`)
+		w := serve(r, SetHeader("ETag", `"v2"`, true))
+
+		expect.That(t,
+			is.EqualTo(w.Code, http.StatusOK),
+			is.EqualTo(w.Body.String(), "0123456789"),
+		)
+	})
+
+	t.Run("if_none_match", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("If-None-Match", `"v1"`)
+		w := serve(r, SetHeader("ETag", `"v1"`, true))
+
+		expect.That(t,
+			is.EqualTo(w.Code, http.StatusNotModified),
+			is.EqualTo(w.Body.Len(), 0),
+		)
+	})
+
+	t.Run("if_modified_since", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("If-Modified-Since", fileModTime.Format(http.TimeFormat))
+		w := serve(r)
+
+		expect.That(t, is.EqualTo(w.Code, http.StatusNotModified))
+	})
+}
+
+func TestFile(t *testing.T) {
+	fsys := fstest.MapFS{
+		"static/report":  {Data: []byte("%PDF-1.4 ..."), ModTime: fileModTime},
+		"static/app.css": {Data: []byte("body {}"), ModTime: fileModTime},
+	}
+
+	t.Run("sniffed", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+		err := File(w, r, fsys, "static/report", Attachment("Bericht März.pdf"))
+
+		expect.That(t,
+			is.NoError(err),
+			is.EqualTo(w.Code, http.StatusOK),
+			is.EqualTo(w.Header().Get("Content-Type"), "application/pdf"),
+			is.EqualTo(w.Header().Get("Last-Modified"), fileModTime.Format(http.TimeFormat)),
+			is.EqualTo(w.Header().Get("Content-Disposition"), `attachment; filename="Bericht M_rz.pdf"; filename*=UTF-8''Bericht%20M%C3%A4rz.pdf`),
+		)
+	})
+
+	t.Run("extension", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+		err := File(w, r, fsys, "static/app.css")
+
+		expect.That(t,
+			is.NoError(err),
+			is.EqualTo(w.Header().Get("Content-Type"), "text/css; charset=utf-8"),
+			is.EqualTo(w.Body.String(), "body {}"),
+		)
+	})
+
+	t.Run("not_found", func(t *testing.T) {
+		err := File(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil), fsys, "static/missing")
+		expect.That(t, is.Error(err, fs.ErrNotExist))
+	})
+
+	t.Run("directory", func(t *testing.T) {
+		err := File(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil), fsys, "static")
+		expect.That(t, is.Error(err, ErrIsDirectory))
+	})
+}
+
+func TestContentDisposition(t *testing.T) {
+	tests := map[string]string{
+		"":               "inline",
+		"report.pdf":     `inline; filename="report.pdf"`,
+		`a "quoted".txt`: `inline; filename="a \"quoted\".txt"`,
+		"€ rates.csv":    `inline; filename="_ rates.csv"; filename*=UTF-8''%E2%82%AC%20rates.csv`,
+	}
+
+	for in, want := range tests {
+		expect.That(t, is.EqualTo(ContentDisposition("inline", in), want))
+	}
+}
@@ -0,0 +1,249 @@
+package response
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"path"
+	"strconv"
+	"sync"
+
+	"github.com/halimath/httputils/bufferedresponse"
+)
+
+// TemplateHook defines a function type that computes a value made available
+// to HTML templates for a single request. Hooks are used to inject common data
+// such as CSRF tokens, session flash messages or CSP nonces, which usually is
+// taken from the request's context.
+type TemplateHook func(r *http.Request) any
+
+// HTMLRenderer renders HTML pages from [html/template] templates loaded from
+// a [fs.FS]. Each page is parsed into its own template set together with the
+// shared templates (i.e. layouts and partials). Parsed sets are cached unless
+// the request's [Config] enables ReloadTemplates (i.e. if DevMode is set to
+// true), in which case templates are reloaded on every render.
+//
+// An HTMLRenderer is safe for concurrent use.
+type HTMLRenderer struct {
+	fsys   fs.FS
+	shared []string
+	layout string
+	funcs  template.FuncMap
+	hooks  map[string]TemplateHook
+
+	lock  sync.RWMutex
+	cache map[string]*template.Template
+}
+
+// HTMLRendererOption defines a mutator type to configure an [HTMLRenderer].
+type HTMLRendererOption func(*HTMLRenderer)
+
+// WithSharedTemplates is an [HTMLRendererOption] that adds the files matching
+// patterns (see [fs.Glob]) to every page's template set. Use this to provide
+// layouts and partials.
+func WithSharedTemplates(patterns ...string) HTMLRendererOption {
+	return func(h *HTMLRenderer) {
+		h.shared = append(h.shared, patterns...)
+	}
+}
+
+// WithLayout is an [HTMLRendererOption] that configures the name of the
+// template executed to render a page. The page usually defines the blocks
+// used by the layout. If no layout is configured, the page's file itself is
+// executed. See [Layout] to override the layout for a single response.
+func WithLayout(name string) HTMLRendererOption {
+	return func(h *HTMLRenderer) {
+		h.layout = name
+	}
+}
+
+// WithFuncs is an [HTMLRendererOption] that adds funcs to every page's
+// template set.
+func WithFuncs(funcs template.FuncMap) HTMLRendererOption {
+	return func(h *HTMLRenderer) {
+		for name, f := range funcs {
+			h.funcs[name] = f
+		}
+	}
+}
+
+// WithTemplateHook is an [HTMLRendererOption] that registers hook as a
+// template function name. The function takes no arguments and returns the
+// value computed by hook for the request being rendered, i.e.
+//
+//	WithTemplateHook("csrfField", func(r *http.Request) any { return csrf.TemplateField(r) })
+//
+// makes the CSRF form field available as {{ csrfField }}.
+func WithTemplateHook(name string, hook TemplateHook) HTMLRendererOption {
+	return func(h *HTMLRenderer) {
+		h.hooks[name] = hook
+		h.funcs[name] = func() any { return nil }
+	}
+}
+
+// NewHTMLRenderer creates a new HTMLRenderer loading templates from fsys.
+func NewHTMLRenderer(fsys fs.FS, opts ...HTMLRendererOption) *HTMLRenderer {
+	h := &HTMLRenderer{
+		fsys:  fsys,
+		funcs: make(template.FuncMap),
+		hooks: make(map[string]TemplateHook),
+		cache: make(map[string]*template.Template),
+	}
+
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	return h
+}
+
+// DefaultHTMLRenderer is the [HTMLRenderer] used by [HTML]. It must be set
+// before HTML is used.
+var DefaultHTMLRenderer *HTMLRenderer
+
+// ErrNoHTMLRenderer is returned from [HTML] if [DefaultHTMLRenderer] has not
+// been set.
+var ErrNoHTMLRenderer = errors.New("no html renderer configured")
+
+// htmlWriter captures the headers and status code set by Options as well as
+// per response configuration of an HTML page.
+type htmlWriter struct {
+	bufferedresponse.ResponseWriter
+
+	layout *string
+}
+
+// Layout is an Option for [HTML] that overrides the layout template to
+// execute for a single response. Use the empty string to render the page
+// without a layout. Other responses ignore this Option.
+func Layout(name string) Option {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		if h, ok := w.(*htmlWriter); ok {
+			h.layout = &name
+		}
+		return nil
+	}
+}
+
+// HTML renders the page name (a path within the renderer's file system) with
+// data and sends it as the response' body. HTML sets content-type to
+// text/html; charset=utf-8 (overwritable, see [Config.HTMLContentType]) and
+// content-length (not overwritable). opts may further customize the response
+// (headers, status code).
+//
+// The page is rendered completely before anything is written to w. Errors
+// loading or executing templates are returned without writing a response, so
+// they can be handled as any other error returned from a handler.
+func (h *HTMLRenderer) HTML(w http.ResponseWriter, r *http.Request, name string, data any, opts ...Option) error {
+	hw := htmlWriter{}
+	for _, opt := range opts {
+		if err := opt(&hw, r); err != nil {
+			return err
+		}
+	}
+
+	cfg := ConfigFromRequest(r)
+
+	t, err := h.load(name, cfg.ReloadTemplates)
+	if err != nil {
+		return err
+	}
+
+	if len(h.hooks) > 0 {
+		if t, err = t.Clone(); err != nil {
+			return err
+		}
+
+		funcs := make(template.FuncMap, len(h.hooks))
+		for n, hook := range h.hooks {
+			funcs[n] = func() any { return hook(r) }
+		}
+		t.Funcs(funcs)
+	}
+
+	entry := path.Base(name)
+	if h.layout != "" {
+		entry = h.layout
+	}
+	if hw.layout != nil {
+		entry = *hw.layout
+		if entry == "" {
+			entry = path.Base(name)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, entry, data); err != nil {
+		return fmt.Errorf("failed to render page %s: %w", name, err)
+	}
+
+	SetHeader("Content-Type", cfg.htmlContentType(), false)(&hw, r)
+	SetHeader("Content-Length", strconv.Itoa(buf.Len()), true)(&hw, r)
+	hw.Write(buf.Bytes())
+
+	return hw.WriteTo(w)
+}
+
+// load returns the template set for page name either from the cache or by
+// parsing it. If reload is true, the cache is bypassed.
+func (h *HTMLRenderer) load(name string, reload bool) (*template.Template, error) {
+	if !reload {
+		h.lock.RLock()
+		t, ok := h.cache[name]
+		h.lock.RUnlock()
+
+		if ok {
+			return t, nil
+		}
+	}
+
+	t, err := h.parse(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if !reload {
+		h.lock.Lock()
+		h.cache[name] = t
+		h.lock.Unlock()
+	}
+
+	return t, nil
+}
+
+func (h *HTMLRenderer) parse(name string) (*template.Template, error) {
+	if _, err := fs.Stat(h.fsys, name); err != nil {
+		return nil, fmt.Errorf("failed to load page %s: %w", name, err)
+	}
+
+	t := template.New(path.Base(name)).Funcs(h.funcs)
+
+	for _, pattern := range h.shared {
+		matches, err := fs.Glob(h.fsys, pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid shared template pattern %s: %w", pattern, err)
+		}
+
+		if len(matches) == 0 {
+			continue
+		}
+
+		if t, err = t.ParseFS(h.fsys, matches...); err != nil {
+			return nil, err
+		}
+	}
+
+	return t.ParseFS(h.fsys, name)
+}
+
+// HTML renders the page name with data using [DefaultHTMLRenderer]. See
+// [HTMLRenderer.HTML] for details.
+func HTML(w http.ResponseWriter, r *http.Request, name string, data any, opts ...Option) error {
+	if DefaultHTMLRenderer == nil {
+		return ErrNoHTMLRenderer
+	}
+	return DefaultHTMLRenderer.HTML(w, r, name, data, opts...)
+}
@@ -0,0 +1,105 @@
+package response
+
+import (
+	"context"
+	"errors"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/halimath/expect"
+	"github.com/halimath/expect/is"
+)
+
+type nonceKey struct{}
+
+func htmlTestFS() fstest.MapFS {
+	return fstest.MapFS{
+		"layouts/base.html":      {Data: []byte(`{{ define "base" }}<title>{{ block "title" . }}Default{{ end }}</title><main>{{ block "content" . }}{{ end }}</main><script nonce="{{ cspNonce }}"></script>{{ end }}`)},
+		"partials/greeting.html": {Data: []byte(`{{ define "greeting" }}Hello, {{ . }}{{ end }}`)},
+		"pages/index.html":       {Data: []byte(`{{ define "title" }}Index{{ end }}{{ define "content" }}{{ template "greeting" .Name }}{{ end }}`)},
+		"pages/plain.html":       {Data: []byte(`<p>{{ upper .Name }}</p>`)},
+		"pages/broken.html":      {Data: []byte(`{{ define "content" }}{{ .Missing.Field }}{{ end }}`)},
+	}
+}
+
+func TestHTMLRenderer(t *testing.T) {
+	fsys := htmlTestFS()
+	h := NewHTMLRenderer(fsys,
+		WithSharedTemplates("layouts/*.html", "partials/*.html"),
+		WithLayout("base"),
+		WithFuncs(template.FuncMap{"upper": strings.ToUpper}),
+		WithTemplateHook("cspNonce", func(r *http.Request) any {
+			nonce, _ := r.Context().Value(nonceKey{}).(string)
+			return nonce
+		}),
+	)
+
+	render := func(name string, data any, opts ...Option) (*httptest.ResponseRecorder, error) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r = r.WithContext(context.WithValue(r.Context(), nonceKey{}, "abc123"))
+		return w, h.HTML(w, r, name, data, opts...)
+	}
+
+	t.Run("layout", func(t *testing.T) {
+		w, err := render("pages/index.html", map[string]string{"Name": "<World>"}, StatusCode(http.StatusCreated))
+		expect.That(t,
+			is.NoError(err),
+			is.EqualTo(w.Code, http.StatusCreated),
+			is.EqualTo(w.Header().Get("Content-Type"), "text/html; charset=utf-8"),
+			is.EqualTo(w.Body.String(), `<title>Index</title><main>Hello, &lt;World&gt;</main><script nonce="abc123"></script>`),
+		)
+	})
+
+	t.Run("without_layout", func(t *testing.T) {
+		w, err := render("pages/plain.html", map[string]string{"Name": "world"}, Layout(""))
+		expect.That(t,
+			is.NoError(err),
+			is.EqualTo(w.Body.String(), `<p>WORLD</p>`),
+		)
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		w, err := render("pages/missing.html", nil)
+		expect.That(t,
+			is.Error(err, fs.ErrNotExist),
+			is.EqualTo(w.Body.Len(), 0),
+		)
+	})
+
+	t.Run("execution_error", func(t *testing.T) {
+		w, err := render("pages/broken.html", struct{}{})
+		expect.That(t,
+			expect.FailNow(is.EqualTo(err != nil, true)),
+			is.StringContaining(err.Error(), "pages/broken.html"),
+			is.EqualTo(w.Body.Len(), 0),
+			is.EqualTo(w.Code, http.StatusOK),
+		)
+	})
+
+	t.Run("cache_and_reload", func(t *testing.T) {
+		_, err := render("pages/plain.html", map[string]string{"Name": "a"}, Layout(""))
+		expect.That(t, expect.FailNow(is.NoError(err)))
+
+		fsys["pages/plain.html"] = &fstest.MapFile{Data: []byte(`<div>{{ .Name }}</div>`)}
+
+		w, _ := render("pages/plain.html", map[string]string{"Name": "a"}, Layout(""))
+		expect.That(t, is.EqualTo(w.Body.String(), `<p>A</p>`))
+
+		DevMode = true
+		defer func() { DevMode = false }()
+
+		w, _ = render("pages/plain.html", map[string]string{"Name": "a"}, Layout(""))
+		expect.That(t, is.EqualTo(w.Body.String(), `<div>a</div>`))
+	})
+}
+
+func TestHTML_noRenderer(t *testing.T) {
+	err := HTML(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil), "index.html", nil)
+	expect.That(t, is.EqualTo(errors.Is(err, ErrNoHTMLRenderer), true))
+}
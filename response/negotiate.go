@@ -0,0 +1,181 @@
+package response
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/halimath/httputils"
+	"github.com/halimath/httputils/internal/accept"
+)
+
+// EncoderFunc defines a function type that encodes a payload into the
+// representation of a single media type. r is the request the payload is
+// sent for; encoders use it to consult the request's [Config].
+type EncoderFunc func(r *http.Request, payload any) ([]byte, error)
+
+// EncoderRegistry contains the [EncoderFunc]s available for content
+// negotiation keyed by media type. The order of registration defines the
+// server's preference, which decides between media types the client finds
+// equally acceptable.
+//
+// An EncoderRegistry is not safe for concurrent modification; register all
+// encoders before handling requests.
+type EncoderRegistry struct {
+	mediaTypes []string
+	encoders   map[string]EncoderFunc
+}
+
+// NewEncoderRegistry creates a new EncoderRegistry containing the following
+// built-in encoders in order of preference:
+//
+//   - application/json using [EncodeJSON]
+//   - application/xml using [EncodeXML]
+//   - application/cbor using [EncodeCBOR]
+//   - application/msgpack using [EncodeMessagePack]
+//   - text/plain using [EncodePlainText]
+func NewEncoderRegistry() *EncoderRegistry {
+	reg := &EncoderRegistry{
+		encoders: make(map[string]EncoderFunc),
+	}
+
+	reg.Register("application/json", EncodeJSON)
+	reg.Register("application/xml", EncodeXML)
+	reg.Register("application/cbor", EncodeCBOR)
+	reg.Register("application/msgpack", EncodeMessagePack)
+	reg.Register("text/plain", EncodePlainText)
+
+	return reg
+}
+
+// DefaultEncoderRegistry is the [EncoderRegistry] used by [Negotiate].
+var DefaultEncoderRegistry = NewEncoderRegistry()
+
+// Register registers enc for mediaType. If an encoder has already been
+// registered for mediaType, it is replaced but keeps its preference. Otherwise
+// mediaType is added with the least preference.
+func (reg *EncoderRegistry) Register(mediaType string, enc EncoderFunc) {
+	mediaType = strings.ToLower(mediaType)
+
+	if _, ok := reg.encoders[mediaType]; !ok {
+		reg.mediaTypes = append(reg.mediaTypes, mediaType)
+	}
+	reg.encoders[mediaType] = enc
+}
+
+// Unregister removes the encoder registered for mediaType.
+func (reg *EncoderRegistry) Unregister(mediaType string) {
+	mediaType = strings.ToLower(mediaType)
+
+	if _, ok := reg.encoders[mediaType]; !ok {
+		return
+	}
+
+	delete(reg.encoders, mediaType)
+	for i, mt := range reg.mediaTypes {
+		if mt == mediaType {
+			reg.mediaTypes = append(reg.mediaTypes[:i], reg.mediaTypes[i+1:]...)
+			break
+		}
+	}
+}
+
+// MediaTypes returns the media types registered with reg in order of
+// preference.
+func (reg *EncoderRegistry) MediaTypes() []string {
+	return append([]string(nil), reg.mediaTypes...)
+}
+
+// Negotiate sends payload using the representation that best matches r's
+// Accept header. Quality values are honored; if multiple media types are
+// equally acceptable, the one registered first is chosen. A request without
+// an Accept header receives the most preferred representation.
+//
+// Negotiate sets content-type (overwritable) and content-length (not
+// overwritable) as well as Vary: Accept. opts may further customize the
+// response (headers, status code). If none of the registered media types is
+// acceptable, a 406 ([http.StatusNotAcceptable]) response with problem
+// details listing the available media types is sent instead.
+//
+// Errors returned from the encoder are handled by sending an [Error] response.
+func (reg *EncoderRegistry) Negotiate(w http.ResponseWriter, r *http.Request, payload any, opts ...Option) error {
+	httputils.AddVary(w.Header(), "Accept")
+
+	mediaType, ok := accept.Negotiate(strings.Join(r.Header.Values("Accept"), ","), reg.mediaTypes...)
+	if !ok {
+		return Problem(w, r, ProblemDetails{
+			Type:   "about:blank",
+			Title:  http.StatusText(http.StatusNotAcceptable),
+			Status: http.StatusNotAcceptable,
+			Detail: "available media types: " + strings.Join(reg.mediaTypes, ", "),
+		})
+	}
+
+	data, err := reg.encoders[mediaType](r, payload)
+	if err != nil {
+		return Error(w, r, err)
+	}
+
+	return Send(w, r, append(opts,
+		SetHeader("Content-Type", mediaType, false),
+		SetHeader("Content-Length", strconv.Itoa(len(data)), true),
+		WriteBody(data),
+	)...)
+}
+
+// Negotiate sends payload using the representation that best matches r's
+// Accept header using [DefaultEncoderRegistry]. See
+// [EncoderRegistry.Negotiate] for details.
+func Negotiate(w http.ResponseWriter, r *http.Request, payload any, opts ...Option) error {
+	return DefaultEncoderRegistry.Negotiate(w, r, payload, opts...)
+}
+
+// RegisterEncoder registers enc for mediaType with [DefaultEncoderRegistry].
+func RegisterEncoder(mediaType string, enc EncoderFunc) {
+	DefaultEncoderRegistry.Register(mediaType, enc)
+}
+
+// --
+
+// EncodeJSON encodes payload as JSON according to r's [Config] (see [JSON]).
+func EncodeJSON(r *http.Request, payload any) ([]byte, error) {
+	return ConfigFromRequest(r).marshalJSON(payload)
+}
+
+// EncodeXML encodes payload using [xml.Marshal] prefixed with the standard
+// XML header. If r's [Config] enables PrettyPrint, the output is indented.
+func EncodeXML(r *http.Request, payload any) ([]byte, error) {
+	var data []byte
+	var err error
+
+	if ConfigFromRequest(r).PrettyPrint {
+		data, err = xml.MarshalIndent(payload, "", "  ")
+	} else {
+		data, err = xml.Marshal(payload)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(xml.Header), data...), nil
+}
+
+// EncodePlainText encodes payload as plain text. Strings and byte slices are
+// used as-is, [fmt.Stringer]s and errors are converted using their String or
+// Error method. All other values are formatted using [fmt.Sprint].
+func EncodePlainText(_ *http.Request, payload any) ([]byte, error) {
+	switch p := payload.(type) {
+	case string:
+		return []byte(p), nil
+	case []byte:
+		return p, nil
+	case fmt.Stringer:
+		return []byte(p.String()), nil
+	case error:
+		return []byte(p.Error()), nil
+	default:
+		return []byte(fmt.Sprint(payload)), nil
+	}
+}
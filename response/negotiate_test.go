@@ -0,0 +1,127 @@
+package response
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/halimath/expect"
+	"github.com/halimath/expect/is"
+)
+
+type negotiatePayload struct {
+	Name string `json:"name" xml:"name"`
+}
+
+func (p negotiatePayload) String() string { return "name: " + p.Name }
+
+func TestNegotiate(t *testing.T) {
+	tests := map[string]struct {
+		accept      string
+		status      int
+		contentType string
+		body        string
+	}{
+		"no_accept":      {"", http.StatusCreated, "application/json", `{"name":"foo"}`},
+		"wildcard":       {"*/*", http.StatusCreated, "application/json", `{"name":"foo"}`},
+		"xml":            {"application/xml", http.StatusCreated, "application/xml", `<?xml version="1.0" encoding="UTF-8"?>` + "\n" + `<negotiatePayload><name>foo</name></negotiatePayload>`},
+		"plain_text":     {"text/*", http.StatusCreated, "text/plain", "name: foo"},
+		"q_values":       {"application/json;q=0.5, text/plain;q=0.8", http.StatusCreated, "text/plain", "name: foo"},
+		"excluded":       {"application/json;q=0, */*;q=0.1", http.StatusCreated, "application/xml", `<?xml version="1.0" encoding="UTF-8"?>` + "\n" + `<negotiatePayload><name>foo</name></negotiatePayload>`},
+		"cbor":           {"application/cbor", http.StatusCreated, "application/cbor", "\xa1\x64name\x63foo"},
+		"msgpack":        {"application/msgpack", http.StatusCreated, "application/msgpack", "\x81\xa4name\xa3foo"},
+		"not_acceptable": {"image/png", http.StatusNotAcceptable, "application/problem+json", ""},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if test.accept != "" {
+				r.Header.Set("Accept", test.accept)
+			}
+
+			err := Negotiate(w, r, negotiatePayload{Name: "foo"}, StatusCode(http.StatusCreated))
+
+			expect.That(t,
+				is.NoError(err),
+				is.EqualTo(w.Code, test.status),
+				is.EqualTo(w.Header().Get("Content-Type"), test.contentType),
+				is.EqualTo(w.Header().Get("Vary"), "Accept"),
+			)
+
+			if test.status != http.StatusNotAcceptable {
+				expect.That(t, is.EqualTo(w.Body.String(), test.body))
+				return
+			}
+
+			var pd ProblemDetails
+			expect.That(t,
+				expect.FailNow(is.NoError(json.Unmarshal(w.Body.Bytes(), &pd))),
+				is.EqualTo(pd.Status, http.StatusNotAcceptable),
+				is.EqualTo(pd.Detail, "available media types: application/json, application/xml, application/cbor, application/msgpack, text/plain"),
+			)
+		})
+	}
+}
+
+func TestEncoderRegistry(t *testing.T) {
+	reg := NewEncoderRegistry()
+	reg.Unregister("application/xml")
+	reg.Register("application/vnd.example+json", func(r *http.Request, payload any) ([]byte, error) {
+		return []byte(`{"example":true}`), nil
+	})
+	reg.Register("text/plain", func(r *http.Request, payload any) ([]byte, error) {
+		return []byte("custom"), nil
+	})
+
+	expect.That(t, is.DeepEqualTo(reg.MediaTypes(), []string{
+		"application/json",
+		"application/cbor",
+		"application/msgpack",
+		"text/plain",
+		"application/vnd.example+json",
+	}))
+
+	t.Run("custom", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Accept", "application/vnd.example+json, application/json;q=0.9")
+
+		err := reg.Negotiate(w, r, nil)
+
+		expect.That(t,
+			is.NoError(err),
+			is.EqualTo(w.Code, http.StatusOK),
+			is.EqualTo(w.Header().Get("Content-Type"), "application/vnd.example+json"),
+			is.EqualTo(w.Body.String(), `{"example":true}`),
+		)
+	})
+
+	t.Run("replaced", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Accept", "text/plain")
+
+		err := reg.Negotiate(w, r, nil)
+
+		expect.That(t,
+			is.NoError(err),
+			is.EqualTo(w.Body.String(), "custom"),
+		)
+	})
+
+	t.Run("removed", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Accept", "application/xml")
+
+		err := reg.Negotiate(w, r, nil)
+
+		expect.That(t,
+			is.NoError(err),
+			is.EqualTo(w.Code, http.StatusNotAcceptable),
+		)
+	})
+}
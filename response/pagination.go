@@ -0,0 +1,294 @@
+package response
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Names of the query parameters used for pagination.
+const (
+	LimitParam  = "limit"
+	CursorParam = "cursor"
+	PageParam   = "page"
+)
+
+// Link relation types used for pagination as registered with IANA.
+const (
+	RelFirst = "first"
+	RelPrev  = "prev"
+	RelNext  = "next"
+	RelLast  = "last"
+)
+
+// PageRequest contains the pagination parameters sent with a request.
+type PageRequest struct {
+	// Maximum number of items to return
+	Limit int
+
+	// Cursor pointing to the first item to return for keyset pagination -
+	// optional. Use a [CursorCodec] to decode the cursor.
+	Cursor string
+
+	// One-based number of the page to return for offset pagination. Defaults
+	// to 1.
+	Page int
+}
+
+// Offset returns the offset of the first item of p's page.
+func (p PageRequest) Offset() int {
+	return (p.Page - 1) * p.Limit
+}
+
+// PageRequestError is returned from [ParsePageRequest] for invalid query
+// parameters. It satisfies errmux's HTTPError and is sent as a 400 response.
+type PageRequestError struct {
+	// Name of the query parameter
+	Param string
+
+	// Description of the problem
+	Detail string
+}
+
+func (e *PageRequestError) Error() string {
+	return fmt.Sprintf("invalid query parameter %s: %s", e.Param, e.Detail)
+}
+
+// StatusCode returns [http.StatusBadRequest].
+func (e *PageRequestError) StatusCode() int { return http.StatusBadRequest }
+
+// Header returns nil.
+func (e *PageRequestError) Header() http.Header { return nil }
+
+// ProblemDetails returns problem details with status 400 describing e.
+func (e *PageRequestError) ProblemDetails() ProblemDetails {
+	return ProblemDetails{
+		Type:   "about:blank",
+		Title:  http.StatusText(http.StatusBadRequest),
+		Status: http.StatusBadRequest,
+		Detail: e.Error(),
+	}
+}
+
+// ParsePageRequest parses the pagination parameters limit, cursor and page
+// from r's query. If limit is not given, defaultLimit is used; values
+// exceeding maxLimit are reduced to maxLimit. Non-numeric values, values less
+// than 1 as well as pages whose [PageRequest.Offset] would overflow an int
+// cause a [PageRequestError]. ParsePageRequest panics if maxLimit is less
+// than 1.
+func ParsePageRequest(r *http.Request, defaultLimit, maxLimit int) (PageRequest, error) {
+	if maxLimit < 1 {
+		panic(fmt.Sprintf("response: invalid maxLimit %d: must be positive", maxLimit))
+	}
+
+	q := r.URL.Query()
+
+	p := PageRequest{
+		Limit:  defaultLimit,
+		Cursor: q.Get(CursorParam),
+		Page:   1,
+	}
+
+	if v := q.Get(LimitParam); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 {
+			return PageRequest{}, &PageRequestError{Param: LimitParam, Detail: "must be a positive integer"}
+		}
+		p.Limit = limit
+	}
+
+	if p.Limit > maxLimit {
+		p.Limit = maxLimit
+	}
+
+	if v := q.Get(PageParam); v != "" {
+		page, err := strconv.Atoi(v)
+		if err != nil || page < 1 {
+			return PageRequest{}, &PageRequestError{Param: PageParam, Detail: "must be a positive integer"}
+		}
+		if p.Limit > 0 && page-1 > math.MaxInt/p.Limit {
+			return PageRequest{}, &PageRequestError{Param: PageParam, Detail: "too large"}
+		}
+		p.Page = page
+	}
+
+	return p, nil
+}
+
+// --
+
+// Link is an Option that adds a Link header as defined in [RFC8288] with
+// relation type rel. The link's target is the request's URL with the query
+// parameters replaced by query; parameters with no values are removed. The
+// target is absolute if the request's URL has been completed using
+// requesturi.Middleware and relative otherwise.
+//
+// [RFC8288]: https://www.rfc-editor.org/rfc/rfc8288
+func Link(rel string, query url.Values) Option {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		u := *r.URL
+		q := u.Query()
+		for k, vals := range query {
+			if len(vals) == 0 {
+				q.Del(k)
+			} else {
+				q[k] = vals
+			}
+		}
+		u.RawQuery = q.Encode()
+
+		w.Header().Add("Link", fmt.Sprintf(`<%s>; rel="%s"`, u.String(), rel))
+		return nil
+	}
+}
+
+// TotalCount is an Option that sets the X-Total-Count header to n.
+func TotalCount(n int) Option {
+	return SetHeader("X-Total-Count", strconv.Itoa(n), true)
+}
+
+// NumberedPagination is an Option that adds first, prev, next and last Link
+// headers (see [Link]) for offset pagination as well as the X-Total-Count
+// header. page is the one-based number of the current page, limit the number
+// of items per page and total the total number of items. Links to prev and
+// next are omitted on the first and last page.
+func NumberedPagination(page, limit, total int) Option {
+	lastPage := 1
+	if limit > 0 && total > 0 {
+		lastPage = (total + limit - 1) / limit
+	}
+
+	pageQuery := func(p int) url.Values {
+		return url.Values{
+			PageParam:   {strconv.Itoa(p)},
+			LimitParam:  {strconv.Itoa(limit)},
+			CursorParam: nil,
+		}
+	}
+
+	opts := []Option{Link(RelFirst, pageQuery(1))}
+	if page > 1 {
+		opts = append(opts, Link(RelPrev, pageQuery(min(page-1, lastPage))))
+	}
+	if page < lastPage {
+		opts = append(opts, Link(RelNext, pageQuery(page+1)))
+	}
+	opts = append(opts, Link(RelLast, pageQuery(lastPage)), TotalCount(total))
+
+	return combine(opts)
+}
+
+// CursorPagination is an Option that adds first, prev and next Link headers
+// (see [Link]) for keyset pagination. limit is the number of items per page,
+// next and prev are the cursors pointing to the next and previous page. Empty
+// cursors omit the corresponding link.
+func CursorPagination(limit int, next, prev string) Option {
+	cursorQuery := func(c string) url.Values {
+		q := url.Values{
+			LimitParam: {strconv.Itoa(limit)},
+			PageParam:  nil,
+		}
+		if c == "" {
+			q[CursorParam] = nil
+		} else {
+			q[CursorParam] = []string{c}
+		}
+		return q
+	}
+
+	opts := []Option{Link(RelFirst, cursorQuery(""))}
+	if prev != "" {
+		opts = append(opts, Link(RelPrev, cursorQuery(prev)))
+	}
+	if next != "" {
+		opts = append(opts, Link(RelNext, cursorQuery(next)))
+	}
+
+	return combine(opts)
+}
+
+// combine combines opts into a single Option.
+func combine(opts []Option) Option {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		for _, opt := range opts {
+			if err := opt(w, r); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// --
+
+// ErrInvalidCursor is returned from [CursorCodec.Decode] if a cursor is
+// malformed or has not been signed with the codec's key.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// CursorCodec encodes and decodes opaque cursors for keyset pagination. A
+// cursor contains the JSON representation of an arbitrary value (i.e. the
+// sort key of the last item returned) signed with HMAC-SHA256 so clients
+// cannot tamper with it. Cursors are not encrypted; do not store secrets in
+// them.
+type CursorCodec struct {
+	key []byte
+}
+
+// NewCursorCodec creates a new CursorCodec signing cursors with key.
+func NewCursorCodec(key []byte) *CursorCodec {
+	return &CursorCodec{key: key}
+}
+
+// Encode encodes v into a cursor using URL safe characters.
+func (c *CursorCodec) Encode(v any) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data) + "." + base64.RawURLEncoding.EncodeToString(c.sign(data)), nil
+}
+
+// Decode verifies cursor and decodes its value into v. It returns an error
+// wrapping [ErrInvalidCursor] if cursor is malformed or its signature is
+// invalid.
+func (c *CursorCodec) Decode(cursor string, v any) error {
+	payload, sig, ok := strings.Cut(cursor, ".")
+	if !ok {
+		return fmt.Errorf("%w: malformed", ErrInvalidCursor)
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+
+	mac, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+
+	if !hmac.Equal(mac, c.sign(data)) {
+		return fmt.Errorf("%w: signature mismatch", ErrInvalidCursor)
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+
+	return nil
+}
+
+func (c *CursorCodec) sign(data []byte) []byte {
+	h := hmac.New(sha256.New, c.key)
+	h.Write(data)
+	return h.Sum(nil)
+}
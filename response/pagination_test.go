@@ -0,0 +1,131 @@
+package response
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/halimath/expect"
+	"github.com/halimath/expect/is"
+)
+
+func TestParsePageRequest(t *testing.T) {
+	tests := map[string]struct {
+		query string
+		want  PageRequest
+		param string
+	}{
+		"defaults":       {"", PageRequest{Limit: 20, Page: 1}, ""},
+		"all":            {"?limit=5&page=3&cursor=abc", PageRequest{Limit: 5, Page: 3, Cursor: "abc"}, ""},
+		"max_limit":      {"?limit=1000", PageRequest{Limit: 100, Page: 1}, ""},
+		"invalid_limit":  {"?limit=foo", PageRequest{}, LimitParam},
+		"negative_limit": {"?limit=-1", PageRequest{}, LimitParam},
+		"invalid_page":   {"?page=0", PageRequest{}, PageParam},
+		"page_overflow":  {"?page=9223372036854775807", PageRequest{}, PageParam},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/items"+test.query, nil)
+			got, err := ParsePageRequest(r, 20, 100)
+
+			if test.param != "" {
+				pe, ok := err.(*PageRequestError)
+				expect.That(t,
+					expect.FailNow(is.EqualTo(ok, true)),
+					is.EqualTo(pe.Param, test.param),
+					is.EqualTo(pe.StatusCode(), http.StatusBadRequest),
+				)
+				return
+			}
+
+			expect.That(t,
+				is.NoError(err),
+				is.DeepEqualTo(got, test.want),
+			)
+		})
+	}
+
+	expect.That(t, is.EqualTo(PageRequest{Limit: 10, Page: 3}.Offset(), 20))
+}
+
+func TestParsePageRequest_invalidMaxLimit(t *testing.T) {
+	defer func() {
+		expect.That(t, is.EqualTo(recover() != nil, true))
+	}()
+
+	ParsePageRequest(httptest.NewRequest(http.MethodGet, "/items", nil), 20, 0)
+}
+
+func TestNumberedPagination(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "https://example.com/items?page=2&limit=10&sort=name", nil)
+
+	err := JSON(w, r, []string{}, NumberedPagination(2, 10, 35))
+
+	expect.That(t,
+		is.NoError(err),
+		is.DeepEqualTo(w.Header().Values("Link"), []string{
+			`<https://example.com/items?limit=10&page=1&sort=name>; rel="first"`,
+			`<https://example.com/items?limit=10&page=1&sort=name>; rel="prev"`,
+			`<https://example.com/items?limit=10&page=3&sort=name>; rel="next"`,
+			`<https://example.com/items?limit=10&page=4&sort=name>; rel="last"`,
+		}),
+		is.EqualTo(w.Header().Get("X-Total-Count"), "35"),
+	)
+}
+
+func TestNumberedPagination_lastPage(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/items", nil)
+
+	err := JSON(w, r, []string{}, NumberedPagination(1, 10, 0))
+
+	expect.That(t,
+		is.NoError(err),
+		is.DeepEqualTo(w.Header().Values("Link"), []string{
+			`</items?limit=10&page=1>; rel="first"`,
+			`</items?limit=10&page=1>; rel="last"`,
+		}),
+		is.EqualTo(w.Header().Get("X-Total-Count"), "0"),
+	)
+}
+
+func TestCursorPagination(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "https://example.com/items?cursor=c1&limit=10", nil)
+
+	err := JSON(w, r, []string{}, CursorPagination(10, "c2", ""))
+
+	expect.That(t,
+		is.NoError(err),
+		is.DeepEqualTo(w.Header().Values("Link"), []string{
+			`<https://example.com/items?limit=10>; rel="first"`,
+			`<https://example.com/items?cursor=c2&limit=10>; rel="next"`,
+		}),
+	)
+}
+
+func TestCursorCodec(t *testing.T) {
+	type position struct {
+		Name string `json:"n"`
+		ID   int    `json:"i"`
+	}
+
+	codec := NewCursorCodec([]byte("secret"))
+
+	cursor, err := codec.Encode(position{Name: "foo", ID: 17})
+	expect.That(t, expect.FailNow(is.NoError(err)))
+
+	var got position
+	expect.That(t,
+		is.NoError(codec.Decode(cursor, &got)),
+		is.DeepEqualTo(got, position{Name: "foo", ID: 17}),
+	)
+
+	other, _ := NewCursorCodec([]byte("other")).Encode(position{Name: "foo", ID: 18})
+
+	for _, invalid := range []string{"", "abc", "abc.def", other, cursor[1:]} {
+		expect.That(t, is.Error(codec.Decode(invalid, &got), ErrInvalidCursor))
+	}
+}
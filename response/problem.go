@@ -0,0 +1,311 @@
+package response
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"slices"
+
+	"github.com/halimath/httputils"
+	"github.com/halimath/httputils/internal/accept"
+)
+
+// problemMembers lists the names of the standard problem details members.
+var problemMembers = []string{"type", "title", "status", "detail", "instance", "errors"}
+
+// extensionNames returns the names of the extension members contained in ext
+// in sorted order. Names of standard members are skipped.
+func extensionNames(ext map[string]any) []string {
+	names := make([]string, 0, len(ext))
+	for name := range ext {
+		if !slices.Contains(problemMembers, name) {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// MarshalJSON implements [json.Marshaler] and encodes pd with its Extensions
+// serialized inline following the standard members in order of their names.
+//
+// MarshalJSON does not escape HTML characters. The [json.Encoder] (or
+// [json.Marshal]) invoking MarshalJSON applies its own escaping setting to the
+// returned data, so [Config.DisableHTMLEscaping] is honored for problem
+// details as well.
+func (pd ProblemDetails) MarshalJSON() ([]byte, error) {
+	type plain ProblemDetails
+
+	data, err := marshalUnescaped(plain(pd))
+	if err != nil {
+		return nil, err
+	}
+
+	names := extensionNames(pd.Extensions)
+	if len(names) == 0 {
+		return data, nil
+	}
+
+	buf := bytes.NewBuffer(data[:len(data)-1])
+	for _, name := range names {
+		value, err := marshalUnescaped(pd.Extensions[name])
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal problem details extension %s: %w", name, err)
+		}
+
+		key, _ := marshalUnescaped(name)
+		buf.WriteByte(',')
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
+
+// marshalUnescaped marshals v to JSON without escaping HTML characters.
+func marshalUnescaped(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+
+	// Remove the newline added by the encoder
+	return bytes.TrimSuffix(buf.Bytes(), []byte{'\n'}), nil
+}
+
+// UnmarshalJSON implements [json.Unmarshaler] and decodes pd from a problem
+// details JSON document. Unknown members are decoded into Extensions.
+// Standard members with a value of the wrong type are ignored as required by
+// [RFC9457].
+//
+// [RFC9457]: https://www.rfc-editor.org/rfc/rfc9457#section-3.1
+func (pd *ProblemDetails) UnmarshalJSON(data []byte) error {
+	var members map[string]any
+	if err := json.Unmarshal(data, &members); err != nil {
+		return err
+	}
+
+	*pd = ProblemDetails{}
+	for name, v := range members {
+		pd.setMember(name, v)
+	}
+
+	return nil
+}
+
+// setMember sets the member name to the generic JSON value v.
+func (pd *ProblemDetails) setMember(name string, v any) {
+	switch name {
+	case "type":
+		pd.Type, _ = v.(string)
+	case "title":
+		pd.Title, _ = v.(string)
+	case "detail":
+		pd.Detail, _ = v.(string)
+	case "instance":
+		pd.Instance, _ = v.(string)
+	case "status":
+		if f, ok := v.(float64); ok && f == math.Trunc(f) {
+			pd.Status = int(f)
+		}
+	case "errors":
+		pd.Errors, _ = v.([]any)
+	default:
+		if pd.Extensions == nil {
+			pd.Extensions = make(map[string]any)
+		}
+		pd.Extensions[name] = v
+	}
+}
+
+// --
+
+// ProblemText contains the human readable members of a problem type in a
+// single language.
+type ProblemText struct {
+	Title  string
+	Detail string
+}
+
+// ProblemType describes a problem type registered with a
+// [ProblemTypeRegistry].
+type ProblemType struct {
+	// URI identifying the problem type - must be given
+	Type string
+
+	// Canonical title used if problem details contain no title
+	Title string
+
+	// Canonical status code used if problem details contain no status
+	Status int
+
+	// Canonical detail used if problem details contain no detail - optional
+	Detail string
+
+	// URL of human readable documentation sent as a Link header with relation
+	// type help - optional. Use this if Type is not resolvable, i.e. a tag URI.
+	Documentation string
+
+	// Translations of Title and Detail keyed by language tag (i.e. de or
+	// de-CH) - optional
+	Translations map[string]ProblemText
+}
+
+// ProblemTypeRegistry contains the [ProblemType]s known to an application
+// keyed by their type URI. It is used by [Problem] and [ProblemXML] to
+// complete and localize problem details. A ProblemTypeRegistry is safe for
+// concurrent use but not safe for concurrent modification; register all
+// problem types during initialization.
+type ProblemTypeRegistry struct {
+	types map[string]ProblemType
+}
+
+// NewProblemTypeRegistry creates a new, empty ProblemTypeRegistry.
+func NewProblemTypeRegistry() *ProblemTypeRegistry {
+	return &ProblemTypeRegistry{
+		types: make(map[string]ProblemType),
+	}
+}
+
+// DefaultProblemTypeRegistry is the [ProblemTypeRegistry] used by [Problem]
+// and [ProblemXML].
+var DefaultProblemTypeRegistry = NewProblemTypeRegistry()
+
+// Register registers pt replacing any problem type registered for the same
+// type URI.
+func (reg *ProblemTypeRegistry) Register(pt ProblemType) {
+	reg.types[pt.Type] = pt
+}
+
+// Lookup returns the problem type registered for typ.
+func (reg *ProblemTypeRegistry) Lookup(typ string) (ProblemType, bool) {
+	pt, ok := reg.types[typ]
+	return pt, ok
+}
+
+// Resolve completes pd for a response to r. If pd's type has been registered,
+// missing title, status and detail are taken from the problem type. If the
+// problem type defines translations, the translation best matching r's
+// Accept-Language header replaces title and detail unless pd defines
+// non-canonical values. For problems of type about:blank without a title, the
+// status code's text is used.
+//
+// Resolve returns the completed problem details and an Option that sets the
+// response headers accompanying them: Content-Language, Vary and Link.
+func (reg *ProblemTypeRegistry) Resolve(r *http.Request, pd ProblemDetails) (ProblemDetails, Option) {
+	var opts []Option
+
+	pt, ok := reg.Lookup(pd.Type)
+	if !ok {
+		if pd.Title == "" && pd.Type == "about:blank" {
+			pd.Title = http.StatusText(pd.Status)
+		}
+		return pd, combine(opts)
+	}
+
+	if pd.Status == 0 {
+		pd.Status = pt.Status
+	}
+
+	title, detail := pt.Title, pt.Detail
+
+	if len(pt.Translations) > 0 {
+		tags := make([]string, 0, len(pt.Translations))
+		for tag := range pt.Translations {
+			tags = append(tags, tag)
+		}
+		slices.Sort(tags)
+
+		if tag, ok := accept.NegotiateLanguage(r.Header.Get("Accept-Language"), tags...); ok {
+			text := pt.Translations[tag]
+			if text.Title != "" {
+				title = text.Title
+			}
+			if text.Detail != "" {
+				detail = text.Detail
+			}
+			opts = append(opts, SetHeader("Content-Language", tag, true))
+		}
+
+		opts = append(opts, func(w http.ResponseWriter, r *http.Request) error {
+			httputils.AddVary(w.Header(), "Accept-Language")
+			return nil
+		})
+	}
+
+	if pd.Title == "" || pd.Title == pt.Title {
+		pd.Title = title
+	}
+	if pd.Detail == "" || pd.Detail == pt.Detail {
+		pd.Detail = detail
+	}
+
+	if pt.Documentation != "" {
+		opts = append(opts, AddHeader("Link", fmt.Sprintf(`<%s>; rel="help"`, pt.Documentation)))
+	}
+
+	return pd, combine(opts)
+}
+
+// RegisterProblemType registers pt with [DefaultProblemTypeRegistry].
+func RegisterProblemType(pt ProblemType) {
+	DefaultProblemTypeRegistry.Register(pt)
+}
+
+// --
+
+// ErrNotProblem is returned from [ParseProblem] if a response does not
+// contain problem details.
+var ErrNotProblem = errors.New("response contains no problem details")
+
+// ParseProblem parses the problem details contained in resp's body. It
+// supports the JSON and XML representations using the content types
+// application/problem+json and application/problem+xml as well as
+// application/json and application/xml. For other content types,
+// [ErrNotProblem] is returned. If the problem details contain no status, the
+// response's status code is used. ParseProblem consumes but does not close
+// resp's body.
+//
+// Use ParseProblem in clients and tests to inspect error responses.
+func ParseProblem(resp *http.Response) (ProblemDetails, error) {
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil {
+		return ProblemDetails{}, ErrNotProblem
+	}
+
+	var unmarshal func([]byte, any) error
+	switch mediaType {
+	case "application/problem+json", "application/json":
+		unmarshal = json.Unmarshal
+	case "application/problem+xml", "application/xml":
+		unmarshal = xml.Unmarshal
+	default:
+		return ProblemDetails{}, ErrNotProblem
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return ProblemDetails{}, err
+	}
+
+	var pd ProblemDetails
+	if err := unmarshal(data, &pd); err != nil {
+		return ProblemDetails{}, fmt.Errorf("failed to parse problem details: %w", err)
+	}
+
+	if pd.Status == 0 {
+		pd.Status = resp.StatusCode
+	}
+
+	return pd, nil
+}
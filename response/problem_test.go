@@ -0,0 +1,256 @@
+package response
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/halimath/expect"
+	"github.com/halimath/expect/is"
+)
+
+func TestProblemDetails_MarshalJSON(t *testing.T) {
+	pd := ProblemDetails{
+		Type:   "https://example.com/problems/out-of-credit",
+		Title:  "You do not have enough credit.",
+		Status: http.StatusForbidden,
+		Extensions: map[string]any{
+			"balance":  30,
+			"accounts": []string{"/account/12345", "/account/67890"},
+			"title":    "ignored",
+		},
+	}
+
+	data, err := json.Marshal(pd)
+	expect.That(t,
+		is.NoError(err),
+		is.EqualTo(string(data), `{"type":"https://example.com/problems/out-of-credit","title":"You do not have enough credit.","status":403,"accounts":["/account/12345","/account/67890"],"balance":30}`),
+	)
+
+	_, err = json.Marshal(ProblemDetails{Extensions: map[string]any{"invalid": func() {}}})
+	expect.That(t, is.EqualTo(err != nil, true))
+}
+
+func TestProblem_htmlEscaping(t *testing.T) {
+	pd := ProblemDetails{
+		Type:       "about:blank",
+		Title:      "<b>",
+		Extensions: map[string]any{"a&b": "<i>"},
+	}
+
+	for disable, want := range map[bool]string{
+		false: `{"type":"about:blank","title":"\u003cb\u003e","status":400,"a\u0026b":"\u003ci\u003e"}`,
+		true:  `{"type":"about:blank","title":"<b>","status":400,"a&b":"<i>"}`,
+	} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r = r.WithContext(ContextWithConfig(r.Context(), Config{DisableHTMLEscaping: disable}))
+
+		pd.Status = http.StatusBadRequest
+		Problem(w, r, pd)
+
+		expect.That(t, is.EqualTo(w.Body.String(), want))
+	}
+}
+
+func TestProblemDetails_UnmarshalJSON(t *testing.T) {
+	var pd ProblemDetails
+	err := json.Unmarshal([]byte(`{"type":"https://example.com/problems/out-of-credit","title":"Out of credit","status":"403","detail":17,"errors":[{"pointer":"#/amount"}],"balance":30}`), &pd)
+
+	expect.That(t,
+		is.NoError(err),
+		is.DeepEqualTo(pd, ProblemDetails{
+			Type:       "https://example.com/problems/out-of-credit",
+			Title:      "Out of credit",
+			Errors:     []any{map[string]any{"pointer": "#/amount"}},
+			Extensions: map[string]any{"balance": 30.0},
+		}),
+	)
+}
+
+func TestProblemDetails_XML(t *testing.T) {
+	pd := ProblemDetails{
+		Type:   "https://example.com/problems/out-of-credit",
+		Title:  "Out of credit",
+		Status: http.StatusForbidden,
+		Errors: []any{"first"},
+		Extensions: map[string]any{
+			"balance":  30,
+			"accounts": []string{"/account/12345", "/account/67890"},
+		},
+	}
+
+	data, err := xml.Marshal(pd)
+	expect.That(t,
+		is.NoError(err),
+		is.EqualTo(string(data), `<problem xmlns="urn:ietf:rfc:7807"><type>https://example.com/problems/out-of-credit</type><title>Out of credit</title><status>403</status><errors><i>first</i></errors><accounts><i>/account/12345</i><i>/account/67890</i></accounts><balance>30</balance></problem>`),
+	)
+
+	var got ProblemDetails
+	err = xml.Unmarshal(data, &got)
+	expect.That(t,
+		is.NoError(err),
+		is.DeepEqualTo(got, ProblemDetails{
+			Type:   "https://example.com/problems/out-of-credit",
+			Title:  "Out of credit",
+			Status: http.StatusForbidden,
+			Errors: []any{"first"},
+			Extensions: map[string]any{
+				"balance":  "30",
+				"accounts": []any{"/account/12345", "/account/67890"},
+			},
+		}),
+	)
+}
+
+func TestProblem_opts(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	err := Problem(w, r, ProblemDetails{Type: "about:blank", Status: http.StatusTooManyRequests},
+		SetHeader("Retry-After", "120", true),
+		SetHeader("Content-Type", "application/json", true),
+	)
+
+	expect.That(t,
+		is.NoError(err),
+		is.EqualTo(w.Code, http.StatusTooManyRequests),
+		is.EqualTo(w.Header().Get("Retry-After"), "120"),
+		is.EqualTo(w.Header().Get("Content-Type"), "application/json"),
+		is.EqualTo(w.Body.String(), `{"type":"about:blank","title":"Too Many Requests","status":429}`),
+	)
+}
+
+func TestProblemTypeRegistry_Resolve(t *testing.T) {
+	reg := NewProblemTypeRegistry()
+	reg.Register(ProblemType{
+		Type:          "tag:example.com,2024:out-of-credit",
+		Title:         "Out of credit",
+		Status:        http.StatusForbidden,
+		Detail:        "Your balance is too low.",
+		Documentation: "https://example.com/docs/errors#out-of-credit",
+		Translations: map[string]ProblemText{
+			"de": {Title: "Kein Guthaben", Detail: "Ihr Guthaben reicht nicht aus."},
+			"fr": {Title: "Crédit insuffisant"},
+		},
+	})
+
+	resolve := func(pd ProblemDetails, acceptLanguage string) (ProblemDetails, http.Header) {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if acceptLanguage != "" {
+			r.Header.Set("Accept-Language", acceptLanguage)
+		}
+
+		pd, opt := reg.Resolve(r, pd)
+
+		w := httptest.NewRecorder()
+		expect.That(t, expect.FailNow(is.NoError(opt(w, r))))
+		return pd, w.Header()
+	}
+
+	t.Run("canonical", func(t *testing.T) {
+		pd, h := resolve(ProblemDetails{Type: "tag:example.com,2024:out-of-credit"}, "")
+		expect.That(t,
+			is.DeepEqualTo(pd, ProblemDetails{
+				Type:   "tag:example.com,2024:out-of-credit",
+				Title:  "Out of credit",
+				Status: http.StatusForbidden,
+				Detail: "Your balance is too low.",
+			}),
+			is.EqualTo(h.Get("Content-Language"), ""),
+			is.EqualTo(h.Get("Vary"), "Accept-Language"),
+			is.EqualTo(h.Get("Link"), `<https://example.com/docs/errors#out-of-credit>; rel="help"`),
+		)
+	})
+
+	t.Run("localized", func(t *testing.T) {
+		pd, h := resolve(ProblemDetails{Type: "tag:example.com,2024:out-of-credit", Title: "Out of credit"}, "de-CH, en;q=0.5")
+		expect.That(t,
+			is.EqualTo(pd.Title, "Kein Guthaben"),
+			is.EqualTo(pd.Detail, "Ihr Guthaben reicht nicht aus."),
+			is.EqualTo(h.Get("Content-Language"), "de"),
+		)
+	})
+
+	t.Run("partial_translation", func(t *testing.T) {
+		pd, _ := resolve(ProblemDetails{Type: "tag:example.com,2024:out-of-credit"}, "fr")
+		expect.That(t,
+			is.EqualTo(pd.Title, "Crédit insuffisant"),
+			is.EqualTo(pd.Detail, "Your balance is too low."),
+		)
+	})
+
+	t.Run("specific_detail", func(t *testing.T) {
+		pd, _ := resolve(ProblemDetails{Type: "tag:example.com,2024:out-of-credit", Status: http.StatusPaymentRequired, Detail: "Balance is 30."}, "de")
+		expect.That(t,
+			is.EqualTo(pd.Status, http.StatusPaymentRequired),
+			is.EqualTo(pd.Title, "Kein Guthaben"),
+			is.EqualTo(pd.Detail, "Balance is 30."),
+		)
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		pd, h := resolve(ProblemDetails{Type: "https://example.com/problems/other", Title: "Other"}, "de")
+		expect.That(t,
+			is.DeepEqualTo(pd, ProblemDetails{Type: "https://example.com/problems/other", Title: "Other"}),
+			is.MapOfLen(h, 0),
+		)
+	})
+}
+
+func TestParseProblem(t *testing.T) {
+	send := func(f func(w http.ResponseWriter, r *http.Request) error) *http.Response {
+		w := httptest.NewRecorder()
+		expect.That(t, expect.FailNow(is.NoError(f(w, httptest.NewRequest(http.MethodGet, "/", nil)))))
+		return w.Result()
+	}
+
+	pd := ProblemDetails{
+		Type:       "https://example.com/problems/out-of-credit",
+		Title:      "Out of credit",
+		Extensions: map[string]any{"balance": "30"},
+	}
+
+	for name, f := range map[string]func(w http.ResponseWriter, r *http.Request, pd ProblemDetails, opts ...Option) error{
+		"json": Problem,
+		"xml":  ProblemXML,
+	} {
+		t.Run(name, func(t *testing.T) {
+			resp := send(func(w http.ResponseWriter, r *http.Request) error {
+				return f(w, r, pd, StatusCode(http.StatusForbidden))
+			})
+
+			got, err := ParseProblem(resp)
+			expect.That(t,
+				is.NoError(err),
+				is.DeepEqualTo(got, ProblemDetails{
+					Type:       "https://example.com/problems/out-of-credit",
+					Title:      "Out of credit",
+					Status:     http.StatusForbidden,
+					Extensions: map[string]any{"balance": "30"},
+				}),
+			)
+		})
+	}
+
+	t.Run("not_problem", func(t *testing.T) {
+		resp := send(func(w http.ResponseWriter, r *http.Request) error {
+			return PlainText(w, r, "error")
+		})
+
+		_, err := ParseProblem(resp)
+		expect.That(t, is.Error(err, ErrNotProblem))
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		resp := send(func(w http.ResponseWriter, r *http.Request) error {
+			return Send(w, r, SetHeader("Content-Type", "application/problem+json", true), WriteBody([]byte("{")))
+		})
+
+		_, err := ParseProblem(resp)
+		expect.That(t, is.EqualTo(err != nil && !errors.Is(err, ErrNotProblem), true))
+	})
+}
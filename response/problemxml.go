@@ -0,0 +1,263 @@
+package response
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ProblemDetailsXMLNamespace defines the XML namespace of problem details as
+// defined in [RFC9457] Appendix B.
+//
+// [RFC9457]: https://www.rfc-editor.org/rfc/rfc9457#appendix-B
+const ProblemDetailsXMLNamespace = "urn:ietf:rfc:7807"
+
+// MarshalXML implements [xml.Marshaler] and encodes pd using the XML format
+// defined in [RFC9457] Appendix B. Errors are converted to their JSON
+// representation first and encoded with arrays using <i> elements; the same
+// applies to Extensions, which are encoded in order of their names. Extension
+// members and object keys that are not valid XML element names without a
+// namespace prefix are omitted.
+//
+// [RFC9457]: https://www.rfc-editor.org/rfc/rfc9457#appendix-B
+func (pd ProblemDetails) MarshalXML(e *xml.Encoder, _ xml.StartElement) error {
+	start := xml.StartElement{Name: xml.Name{Space: ProblemDetailsXMLNamespace, Local: "problem"}}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+
+	fields := []struct {
+		name, value string
+	}{
+		{"type", pd.Type},
+		{"title", pd.Title},
+		{"status", statusString(pd.Status)},
+		{"detail", pd.Detail},
+		{"instance", pd.Instance},
+	}
+
+	for _, f := range fields {
+		if f.value == "" {
+			continue
+		}
+		if err := e.EncodeElement(f.value, xml.StartElement{Name: xml.Name{Local: f.name}}); err != nil {
+			return err
+		}
+	}
+
+	if len(pd.Errors) > 0 {
+		if err := encodeXMLMember(e, "errors", pd.Errors); err != nil {
+			return err
+		}
+	}
+
+	for _, name := range extensionNames(pd.Extensions) {
+		if !isXMLName(name) {
+			continue
+		}
+		if err := encodeXMLMember(e, name, pd.Extensions[name]); err != nil {
+			return err
+		}
+	}
+
+	return e.EncodeToken(start.End())
+}
+
+// encodeXMLMember encodes the member v named name. v is converted into its
+// generic JSON representation first to support arbitrary types.
+func encodeXMLMember(e *xml.Encoder, name string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	var generic any
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return err
+	}
+
+	return encodeXMLValue(e, name, generic)
+}
+
+// isXMLName reports whether name is a valid XML element name without a
+// namespace prefix, i.e. a NCName as defined in [Namespaces in XML].
+//
+// [Namespaces in XML]: https://www.w3.org/TR/xml-names/#NT-NCName
+func isXMLName(name string) bool {
+	if name == "" {
+		return false
+	}
+
+	for i, c := range name {
+		switch {
+		case c == '_' || unicode.IsLetter(c):
+		case i > 0 && (c == '-' || c == '.' || c == '\u00B7' || unicode.IsDigit(c) || unicode.Is(unicode.Mn, c) || unicode.Is(unicode.Mc, c)):
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+func statusString(status int) string {
+	if status == 0 {
+		return ""
+	}
+	return strconv.Itoa(status)
+}
+
+// encodeXMLValue encodes the generic JSON value v as an element named name.
+func encodeXMLValue(e *xml.Encoder, name string, v any) error {
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+
+	switch val := v.(type) {
+	case []any:
+		if err := e.EncodeToken(start); err != nil {
+			return err
+		}
+		for _, item := range val {
+			if err := encodeXMLValue(e, "i", item); err != nil {
+				return err
+			}
+		}
+		return e.EncodeToken(start.End())
+
+	case map[string]any:
+		if err := e.EncodeToken(start); err != nil {
+			return err
+		}
+
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			if isXMLName(k) {
+				keys = append(keys, k)
+			}
+		}
+		slices.Sort(keys)
+
+		for _, k := range keys {
+			if err := encodeXMLValue(e, k, val[k]); err != nil {
+				return err
+			}
+		}
+		return e.EncodeToken(start.End())
+
+	case nil:
+		return e.EncodeElement("", start)
+
+	case float64:
+		return e.EncodeElement(strconv.FormatFloat(val, 'f', -1, 64), start)
+
+	default:
+		return e.EncodeElement(val, start)
+	}
+}
+
+// UnmarshalXML implements [xml.Unmarshaler] and decodes pd from the XML
+// format defined in [RFC9457] Appendix B. Elements containing only <i>
+// elements are decoded as arrays, elements containing other elements as maps
+// and all other elements as strings. Unknown elements are decoded into
+// Extensions; standard members with an invalid value are ignored.
+//
+// [RFC9457]: https://www.rfc-editor.org/rfc/rfc9457#appendix-B
+func (pd *ProblemDetails) UnmarshalXML(d *xml.Decoder, _ xml.StartElement) error {
+	*pd = ProblemDetails{}
+
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			v, err := decodeXMLValue(d)
+			if err != nil {
+				return err
+			}
+
+			// XML carries the status as text
+			if s, ok := v.(string); ok && t.Name.Local == "status" {
+				if status, err := strconv.Atoi(strings.TrimSpace(s)); err == nil {
+					v = float64(status)
+				}
+			}
+
+			pd.setMember(t.Name.Local, v)
+
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// decodeXMLValue decodes the content of the element just started into a
+// generic value.
+func decodeXMLValue(d *xml.Decoder) (any, error) {
+	var text strings.Builder
+	var names []string
+	var values []any
+
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.CharData:
+			text.Write(t)
+
+		case xml.StartElement:
+			v, err := decodeXMLValue(d)
+			if err != nil {
+				return nil, err
+			}
+			names = append(names, t.Name.Local)
+			values = append(values, v)
+
+		case xml.EndElement:
+			if len(names) == 0 {
+				return text.String(), nil
+			}
+
+			if !slices.ContainsFunc(names, func(n string) bool { return n != "i" }) {
+				return values, nil
+			}
+
+			m := make(map[string]any, len(names))
+			for i, n := range names {
+				m[n] = values[i]
+			}
+			return m, nil
+		}
+	}
+}
+
+// ProblemXML sends problemDetails as a XML response using content-type
+// application/problem+xml (overwritable) as defined by [RFC9457] Appendix B.
+// See [Problem] for how the status code and missing members are determined.
+//
+// [RFC9457]: https://www.rfc-editor.org/rfc/rfc9457#appendix-B
+func ProblemXML(w http.ResponseWriter, r *http.Request, problemDetails ProblemDetails, opts ...Option) error {
+	problemDetails, resolved := DefaultProblemTypeRegistry.Resolve(r, problemDetails)
+
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	if err := xml.NewEncoder(&b).Encode(problemDetails); err != nil {
+		return Error(w, r, err)
+	}
+
+	return Send(w, r, append(opts,
+		resolved,
+		SetHeader("Content-Type", "application/problem+xml", false),
+		SetHeader("Content-Length", strconv.Itoa(b.Len()), true),
+		StatusCode(problemStatus(problemDetails)),
+		WriteBody([]byte(b.String())),
+	)...)
+}
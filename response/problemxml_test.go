@@ -0,0 +1,73 @@
+package response
+
+import (
+	"encoding/xml"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/halimath/expect"
+	"github.com/halimath/expect/is"
+)
+
+func TestProblemXML(t *testing.T) {
+	pd := ProblemDetails{
+		Type:   "https://example.com/problem/test",
+		Title:  "Test <Problem>",
+		Status: http.StatusUnprocessableEntity,
+		Errors: []any{
+			map[string]any{"pointer": "#/name", "detail": "is required"},
+			"other",
+		},
+	}
+
+	got, err := apply(func(w http.ResponseWriter, r *http.Request) error {
+		return ProblemXML(w, r, pd)
+	})
+
+	expect.That(t,
+		is.NoError(err),
+		is.EqualToStringByLines(got, `HTTP/1.1 422 Unprocessable Entity
+			Content-Length: 273
+			Content-Type: application/problem+xml
+
+			<?xml version="1.0" encoding="UTF-8"?>
+			<problem xmlns="urn:ietf:rfc:7807"><type>https://example.com/problem/test</type><title>Test &lt;Problem&gt;</title><status>422</status><errors><i><detail>is required</detail><pointer>#/name</pointer></i><i>other</i></errors></problem>`, is.DedentLines, func(s string) string { return strings.ReplaceAll(s, "\r", "") }),
+	)
+}
+
+func TestProblemDetails_MarshalXML_invalidNames(t *testing.T) {
+	pd := ProblemDetails{
+		Title: "Test",
+		Extensions: map[string]any{
+			"a b":   1,
+			"1x":    2,
+			"x:y":   3,
+			"valid": map[string]any{"ok": true, "not ok": false},
+		},
+	}
+
+	data, err := xml.Marshal(pd)
+	expect.That(t,
+		is.NoError(err),
+		is.EqualTo(string(data), `<problem xmlns="urn:ietf:rfc:7807"><title>Test</title><valid><ok>true</ok></valid></problem>`),
+	)
+}
+
+func TestIsXMLName(t *testing.T) {
+	tab := map[string]bool{
+		"name":     true,
+		"_x1.y-z":  true,
+		"größe":    true,
+		"":         false,
+		"a b":      false,
+		"1x":       false,
+		"x:y":      false,
+		"-x":       false,
+		"<script>": false,
+	}
+
+	for in, want := range tab {
+		expect.That(t, is.EqualTo(isXMLName(in), want))
+	}
+}
@@ -2,7 +2,7 @@
 package response
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/textproto"
@@ -82,19 +82,30 @@ func Send(w http.ResponseWriter, r *http.Request, opts ...Option) error {
 }
 
 // DevMode can be set to true to enable error responses for development/debugging. Otherwise, errors are
-// discarded.
+// discarded. DevMode defines the settings of [DefaultConfig] and thus only applies to requests without a
+// [Config] attached to their context.
 var DevMode = false
 
+// StackTracer may be implemented by errors that captured the stack trace of
+// their origin, such as errors created from recovered panics. The stack trace
+// is given as program counters as returned from [runtime.Callers].
+type StackTracer interface {
+	StackTrace() []uintptr
+}
+
 // Error sends an error response. By default, a status code 500
 // [http.StatusInternalServerError] is used but opts may replace this with a
 // different status code.
 //
-// This operation pays respect to DevMode. If DevMode is false (the default),
-// Error simply sends an empty response with the respective status code. If
-// DevMode is set to true, this method sends the errors description as a plain
-// text response simplifying development.
+// This operation pays respect to the request's [Config]. If ErrorDetails is
+// false (the default), Error simply sends an empty response with the
+// respective status code. If ErrorDetails is set to true (i.e. by setting
+// DevMode), this method sends the errors description as a plain
+// text response simplifying development. The description includes a stack
+// trace which is taken from err if err (or any error it wraps) implements
+// [StackTracer] or captured when calling Error otherwise.
 func Error(w http.ResponseWriter, r *http.Request, err error, opts ...Option) error {
-	if DevMode {
+	if ConfigFromRequest(r).ErrorDetails {
 		return PlainText(w, r, buildErrorResponse(err), append(opts, StatusCode(http.StatusInternalServerError))...)
 	}
 
@@ -102,13 +113,26 @@ func Error(w http.ResponseWriter, r *http.Request, err error, opts ...Option) er
 }
 
 func buildErrorResponse(err error) string {
-	pc := make([]uintptr, 256)
-	runtime.Callers(3, pc)
-	frames := runtime.CallersFrames(pc)
+	var pc []uintptr
+
+	var st StackTracer
+	if errors.As(err, &st) {
+		pc = st.StackTrace()
+	} else {
+		pc = make([]uintptr, 256)
+		n := runtime.Callers(3, pc)
+		pc = pc[:n]
+	}
+
+	return fmt.Sprintf("%s (%T)\n%s", err.Error(), err, FormatStackTrace(pc))
+}
 
+// FormatStackTrace formats the stack trace given as program counters (as
+// returned from [runtime.Callers]) with one line per frame.
+func FormatStackTrace(pc []uintptr) string {
 	var sb strings.Builder
 
-	fmt.Fprintf(&sb, "%s (%T)\n", err.Error(), err)
+	frames := runtime.CallersFrames(pc)
 	for {
 		f, ok := frames.Next()
 		if !ok {
@@ -134,11 +158,12 @@ func NotFound(w http.ResponseWriter, r *http.Request, opts ...Option) error {
 
 // PlainText sends a response with a plain text body. It sends body as the
 // response' body. opts may further customize the response (headers, status code).
-// PlainText sets content-type to text/plain (overwritable) and content-length
-// to the body's length (not overwritable).
+// PlainText sets content-type to text/plain (overwritable, see
+// [Config.TextContentType]) and content-length to the body's length (not
+// overwritable).
 func PlainText(w http.ResponseWriter, r *http.Request, body string, opts ...Option) error {
 	return Send(w, r, append(opts,
-		SetHeader("Content-Type", "text/plain", false),
+		SetHeader("Content-Type", ConfigFromRequest(r).textContentType(), false),
 		SetHeader("Content-Length", strconv.Itoa(len(body)), true),
 		WriteBody([]byte(body)),
 	)...)
@@ -157,28 +182,25 @@ func NoContent(w http.ResponseWriter, r *http.Request, opts ...Option) error {
 	return Send(w, r, append(opts, SetHeader("Content-Length", "0", true), StatusCode(http.StatusNoContent))...)
 }
 
-// JSON sends a response with content-type application/json. It uses [json.Marshal]
-// to marshal payload to JSON bytes and sends them as the response' body. JSON
-// sets both header content-type and content-length. opts may further customize
-// the response as well as overwrite the content-type (to some other content
-// type based on JSON).
+// JSON sends a response with content-type application/json. It uses a
+// [json.Encoder] to marshal payload to JSON bytes and sends them as the
+// response' body. JSON sets both header content-type and content-length. opts
+// may further customize the response as well as overwrite the content-type (to
+// some other content type based on JSON).
 //
-// If DevMode is set to true, JSON output is pretty printed
+// The request's [Config] defines whether output is pretty printed (i.e. if
+// DevMode is set to true), whether HTML characters are escaped and the default
+// content-type.
 func JSON(w http.ResponseWriter, r *http.Request, payload any, opts ...Option) error {
-	var data []byte
-	var err error
+	cfg := ConfigFromRequest(r)
 
-	if DevMode {
-		data, err = json.MarshalIndent(payload, "", "  ")
-	} else {
-		data, err = json.Marshal(payload)
-	}
+	data, err := cfg.marshalJSON(payload)
 	if err != nil {
 		return Error(w, r, err)
 	}
 
 	return Send(w, r, append(opts,
-		SetHeader("Content-Type", "application/json", false),
+		SetHeader("Content-Type", cfg.jsonContentType(), false),
 		SetHeader("Content-Length", strconv.Itoa(len(data)), true),
 		WriteBody(data),
 	)...)
@@ -186,14 +208,16 @@ func JSON(w http.ResponseWriter, r *http.Request, payload any, opts ...Option) e
 
 // ProblemDetails defines a problem details object as defined by [RFC9457].
 // ProblemDetails defines an Errors field which may be used to deliver additional
-// error information as an extension.
+// error information as an extension. Other extension members are given with
+// Extensions and serialized inline with the standard members.
 //
 // [RFC9457]: https://www.rfc-editor.org/rfc/rfc9457
 type ProblemDetails struct {
 	// Type discriminator - must be given
 	Type string `json:"type"`
 
-	// Human readable title - must be given
+	// Human readable title - must be given unless Type has been registered
+	// with a [ProblemTypeRegistry]
 	Title string `json:"title"`
 
 	// Status code - may be set. If set, also defines the HTTP status code
@@ -207,17 +231,34 @@ type ProblemDetails struct {
 
 	// Additional user defined error information - optional and used as an extension
 	Errors []any `json:"errors,omitempty"`
+
+	// Additional extension members keyed by name - optional. Names of the
+	// standard members above are ignored.
+	Extensions map[string]any `json:"-"`
 }
 
 // Problem sends problemDetails as a JSON response as defined by [RFC9457].
+// Problem sets content-type to application/problem+json (overwritable) and
+// uses problemDetails' status (defaulting to 500) as the status code unless
+// opts define another one. Missing members as well as localized title and
+// detail are taken from [DefaultProblemTypeRegistry] (see
+// [ProblemTypeRegistry.Resolve]).
 //
 // [RFC9457]: https://www.rfc-editor.org/rfc/rfc9457
 func Problem(w http.ResponseWriter, r *http.Request, problemDetails ProblemDetails, opts ...Option) error {
-	status := http.StatusInternalServerError
+	problemDetails, resolved := DefaultProblemTypeRegistry.Resolve(r, problemDetails)
 
-	if problemDetails.Status != 0 {
-		status = problemDetails.Status
-	}
+	return JSON(w, r, problemDetails, append(opts,
+		resolved,
+		SetHeader("Content-Type", "application/problem+json", false),
+		StatusCode(problemStatus(problemDetails)),
+	)...)
+}
 
-	return JSON(w, r, problemDetails, SetHeader("Content-Type", "application/problem+json", true), StatusCode(status))
+// problemStatus returns the status code to send for pd.
+func problemStatus(pd ProblemDetails) int {
+	if pd.Status != 0 {
+		return pd.Status
+	}
+	return http.StatusInternalServerError
 }
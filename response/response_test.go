@@ -150,6 +150,37 @@ func TestError(t *testing.T) {
 	})
 }
 
+type stackTraceError []uintptr
+
+func (e stackTraceError) Error() string         { return "kaboom" }
+func (e stackTraceError) StackTrace() []uintptr { return e }
+
+func TestError_stackTracer(t *testing.T) {
+	DevMode = true
+	defer func() { DevMode = false }()
+
+	pc := make([]uintptr, 1)
+	runtime.Callers(1, pc)
+	err := stackTraceError(pc)
+
+	got, e := apply(func(w http.ResponseWriter, r *http.Request) error {
+		return Error(w, r, fmt.Errorf("wrapped: %w", err))
+	})
+
+	body := "wrapped: kaboom (*fmt.wrapError)\n" + FormatStackTrace(pc)
+
+	want := fmt.Sprintf(`HTTP/1.1 500 Internal Server Error
+		Content-Length: %d
+		Content-Type: text/plain
+
+		%s`, len(body), body)
+
+	expect.That(t,
+		is.NoError(e),
+		is.EqualToStringByLines(got, want, is.DedentLines, func(s string) string { return strings.ReplaceAll(s, "\r", "") }),
+	)
+}
+
 func TestNotModified(t *testing.T) {
 	got, err := apply(func(w http.ResponseWriter, r *http.Request) error {
 		return NotModified(w, r)
@@ -0,0 +1,364 @@
+package response
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/halimath/httputils/bufferedresponse"
+)
+
+// Event implements a single event sent as part of a Server-Sent Events stream
+// as specified in the [HTML Living Standard].
+//
+// [HTML Living Standard]: https://html.spec.whatwg.org/multipage/server-sent-events.html
+type Event struct {
+	// ID of the event - optional. Clients send the ID of the last event
+	// received when reconnecting.
+	ID string
+
+	// Name of the event - optional. If empty, clients dispatch the event as
+	// message.
+	Event string
+
+	// Reconnection time clients should use - optional.
+	Retry time.Duration
+
+	// Data of the event. Data may contain multiple lines. Events without data
+	// only update the client's last event ID and reconnection time.
+	Data string
+}
+
+// ErrInvalidEvent is returned when sending an [Event] which cannot be
+// represented in an event stream.
+var ErrInvalidEvent = errors.New("invalid event")
+
+// MarshalText implements [encoding.TextMarshaler] and returns e in the event
+// stream format including the terminating blank line.
+func (e Event) MarshalText() ([]byte, error) {
+	if strings.ContainsAny(e.ID, "\r\n\x00") {
+		return nil, fmt.Errorf("%w: id must not contain line breaks or NUL", ErrInvalidEvent)
+	}
+
+	if strings.ContainsAny(e.Event, "\r\n") {
+		return nil, fmt.Errorf("%w: event must not contain line breaks", ErrInvalidEvent)
+	}
+
+	var b strings.Builder
+
+	if e.ID != "" {
+		b.WriteString("id: ")
+		b.WriteString(e.ID)
+		b.WriteByte('\n')
+	}
+
+	if e.Event != "" {
+		b.WriteString("event: ")
+		b.WriteString(e.Event)
+		b.WriteByte('\n')
+	}
+
+	if e.Retry > 0 {
+		b.WriteString("retry: ")
+		b.WriteString(strconv.FormatInt(e.Retry.Milliseconds(), 10))
+		b.WriteByte('\n')
+	}
+
+	if e.Data != "" || b.Len() == 0 {
+		for _, l := range splitLines(e.Data) {
+			b.WriteString("data: ")
+			b.WriteString(l)
+			b.WriteByte('\n')
+		}
+	}
+
+	b.WriteByte('\n')
+
+	return []byte(b.String()), nil
+}
+
+// splitLines splits s into lines separated by CRLF, LF or CR.
+func splitLines(s string) []string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = strings.ReplaceAll(s, "\r", "\n")
+	return strings.Split(s, "\n")
+}
+
+// --
+
+// ReplayBuffer stores events to replay them to clients resuming an event
+// stream using the Last-Event-ID request header. Implementations must be safe
+// for concurrent use.
+type ReplayBuffer interface {
+	// Add adds e to the buffer.
+	Add(e Event)
+
+	// Since returns all events added after the event with ID id.
+	Since(id string) []Event
+}
+
+// MemoryReplayBuffer implements a [ReplayBuffer] that keeps a fixed number of
+// most recent events in memory.
+type MemoryReplayBuffer struct {
+	lock   sync.Mutex
+	size   int
+	events []Event
+}
+
+// NewMemoryReplayBuffer creates a new MemoryReplayBuffer keeping the size most
+// recent events.
+func NewMemoryReplayBuffer(size int) *MemoryReplayBuffer {
+	return &MemoryReplayBuffer{
+		size:   size,
+		events: make([]Event, 0, size),
+	}
+}
+
+// Add adds e to b. If b is full, the oldest event is discarded.
+func (b *MemoryReplayBuffer) Add(e Event) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.size <= 0 {
+		return
+	}
+
+	if len(b.events) == b.size {
+		copy(b.events, b.events[1:])
+		b.events = b.events[:len(b.events)-1]
+	}
+
+	b.events = append(b.events, e)
+}
+
+// Since returns all events added after the event with ID id. If b does not
+// contain an event with ID id (i.e. because it has been discarded), all events
+// are returned.
+func (b *MemoryReplayBuffer) Since(id string) []Event {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	start := 0
+	for i := len(b.events) - 1; i >= 0; i-- {
+		if b.events[i].ID == id {
+			start = i + 1
+			break
+		}
+	}
+
+	return append([]Event(nil), b.events[start:]...)
+}
+
+// --
+
+// DefaultHeartbeatInterval defines the default interval to send heartbeat
+// comments on an [EventStream].
+const DefaultHeartbeatInterval = 15 * time.Second
+
+// ErrEventStreamClosed is returned when sending to an [EventStream] that has
+// been closed.
+var ErrEventStreamClosed = errors.New("event stream closed")
+
+// eventStreamWriter captures the headers and status code set by Options as
+// well as the configuration of an event stream.
+type eventStreamWriter struct {
+	bufferedresponse.ResponseWriter
+
+	heartbeat time.Duration
+	replay    ReplayBuffer
+}
+
+// Heartbeat is an Option for [NewEventStream] that configures the interval to
+// send heartbeat comments keeping idle connections open. An interval of 0
+// disables heartbeats. Other responses ignore this Option.
+func Heartbeat(d time.Duration) Option {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		if s, ok := w.(*eventStreamWriter); ok {
+			s.heartbeat = d
+		}
+		return nil
+	}
+}
+
+// Replay is an Option for [NewEventStream] that replays the events stored in
+// b to clients sending a Last-Event-ID header. Other responses ignore this
+// Option.
+func Replay(b ReplayBuffer) Option {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		if s, ok := w.(*eventStreamWriter); ok {
+			s.replay = b
+		}
+		return nil
+	}
+}
+
+// EventStream implements a Server-Sent Events stream. An EventStream is safe
+// for concurrent use.
+type EventStream struct {
+	w           http.ResponseWriter
+	rc          *http.ResponseController
+	ctx         context.Context
+	lastEventID string
+
+	lock   sync.Mutex
+	closed bool
+
+	stop          chan struct{}
+	heartbeatDone chan struct{}
+}
+
+// NewEventStream starts a Server-Sent Events stream on w. It sets
+// content-type to text/event-stream and, unless set by opts, cache-control to
+// no-cache and sends the response header right away. opts may further
+// customize the response (headers, status code) and configure the stream (see
+// [Heartbeat] and [Replay]). Any write deadline set for the connection is
+// removed.
+//
+// If the request carries a Last-Event-ID header and a [ReplayBuffer] has been
+// configured, all events from the buffer following that ID are sent before
+// NewEventStream returns.
+//
+// The stream ends when the client disconnects (i.e. r's context is done) or
+// when Close is called. Handlers must call Close before returning.
+func NewEventStream(w http.ResponseWriter, r *http.Request, opts ...Option) (*EventStream, error) {
+	cfg := eventStreamWriter{
+		heartbeat: DefaultHeartbeatInterval,
+	}
+
+	for _, opt := range opts {
+		if err := opt(&cfg, r); err != nil {
+			return nil, err
+		}
+	}
+
+	SetHeader("Content-Type", "text/event-stream", true)(&cfg, r)
+	SetHeader("Cache-Control", "no-cache", false)(&cfg, r)
+
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return nil, err
+	}
+
+	if err := cfg.WriteTo(w); err != nil {
+		return nil, err
+	}
+
+	if err := rc.Flush(); err != nil {
+		return nil, fmt.Errorf("failed to start event stream: %w", err)
+	}
+
+	s := &EventStream{
+		w:             w,
+		rc:            rc,
+		ctx:           r.Context(),
+		lastEventID:   r.Header.Get("Last-Event-ID"),
+		stop:          make(chan struct{}),
+		heartbeatDone: make(chan struct{}),
+	}
+
+	if cfg.heartbeat > 0 {
+		go s.sendHeartbeats(cfg.heartbeat)
+	} else {
+		close(s.heartbeatDone)
+	}
+
+	if cfg.replay != nil && s.lastEventID != "" {
+		for _, e := range cfg.replay.Since(s.lastEventID) {
+			if err := s.Send(e); err != nil {
+				s.Close()
+				return nil, err
+			}
+		}
+	}
+
+	return s, nil
+}
+
+// LastEventID returns the value of the Last-Event-ID request header, which is
+// sent by clients resuming a stream.
+func (s *EventStream) LastEventID() string { return s.lastEventID }
+
+// Done returns a channel that is closed when the client disconnects.
+func (s *EventStream) Done() <-chan struct{} { return s.ctx.Done() }
+
+// Send sends e to the client. It returns the context's error if the client
+// disconnected and [ErrEventStreamClosed] if s has been closed.
+func (s *EventStream) Send(e Event) error {
+	data, err := e.MarshalText()
+	if err != nil {
+		return err
+	}
+
+	return s.write(data)
+}
+
+// Comment sends a comment, which is ignored by clients.
+func (s *EventStream) Comment(comment string) error {
+	var b strings.Builder
+	for _, l := range splitLines(comment) {
+		b.WriteString(": ")
+		b.WriteString(l)
+		b.WriteByte('\n')
+	}
+	b.WriteByte('\n')
+
+	return s.write([]byte(b.String()))
+}
+
+func (s *EventStream) write(data []byte) error {
+	if err := s.ctx.Err(); err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.closed {
+		return ErrEventStreamClosed
+	}
+
+	if _, err := s.w.Write(data); err != nil {
+		return err
+	}
+
+	return s.rc.Flush()
+}
+
+// Close stops sending heartbeats and closes s. Close does not return before
+// heartbeats have been stopped, so no data is written to the underlying
+// ResponseWriter after Close returned. Calling Close multiple times is safe.
+func (s *EventStream) Close() {
+	s.lock.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.stop)
+	}
+	s.lock.Unlock()
+
+	<-s.heartbeatDone
+}
+
+func (s *EventStream) sendHeartbeats(interval time.Duration) {
+	defer close(s.heartbeatDone)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Comment("heartbeat"); err != nil {
+				return
+			}
+		}
+	}
+}
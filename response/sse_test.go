@@ -0,0 +1,139 @@
+package response
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/halimath/expect"
+	"github.com/halimath/expect/is"
+)
+
+func TestEvent_MarshalText(t *testing.T) {
+	tests := map[string]struct {
+		event Event
+		want  string
+		err   error
+	}{
+		"data":       {Event{Data: "hello"}, "data: hello\n\n", nil},
+		"empty":      {Event{}, "data: \n\n", nil},
+		"all_fields": {Event{ID: "1", Event: "update", Retry: 3 * time.Second, Data: "a\nb\r\nc"}, "id: 1\nevent: update\nretry: 3000\ndata: a\ndata: b\ndata: c\n\n", nil},
+		"id_only":    {Event{ID: "7"}, "id: 7\n\n", nil},
+		"invalid_id": {Event{ID: "1\n2"}, "", ErrInvalidEvent},
+		"invalid_ev": {Event{Event: "a\rb"}, "", ErrInvalidEvent},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := test.event.MarshalText()
+			expect.That(t,
+				is.Error(err, test.err),
+				is.EqualTo(string(got), test.want),
+			)
+		})
+	}
+}
+
+func TestMemoryReplayBuffer(t *testing.T) {
+	b := NewMemoryReplayBuffer(3)
+	for _, id := range []string{"1", "2", "3", "4"} {
+		b.Add(Event{ID: id})
+	}
+
+	expect.That(t,
+		is.DeepEqualTo(b.Since("2"), []Event{{ID: "3"}, {ID: "4"}}),
+		is.DeepEqualTo(b.Since("4"), []Event{}),
+		is.DeepEqualTo(b.Since("1"), []Event{{ID: "2"}, {ID: "3"}, {ID: "4"}}),
+	)
+}
+
+func TestEventStream(t *testing.T) {
+	t.Run("send", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+		s, err := NewEventStream(w, r, Heartbeat(0), AddHeader("X-Foo", "bar"))
+		expect.That(t, expect.FailNow(is.NoError(err)))
+
+		expect.That(t,
+			is.NoError(s.Send(Event{ID: "1", Data: "hello"})),
+			is.NoError(s.Comment("note")),
+		)
+		s.Close()
+
+		expect.That(t,
+			is.Error(s.Send(Event{Data: "late"}), ErrEventStreamClosed),
+			is.EqualTo(w.Code, http.StatusOK),
+			is.EqualTo(w.Header().Get("Content-Type"), "text/event-stream"),
+			is.EqualTo(w.Header().Get("Cache-Control"), "no-cache"),
+			is.EqualTo(w.Header().Get("X-Foo"), "bar"),
+			is.EqualTo(w.Body.String(), "id: 1\ndata: hello\n\n: note\n\n"),
+		)
+	})
+
+	t.Run("replay", func(t *testing.T) {
+		b := NewMemoryReplayBuffer(10)
+		b.Add(Event{ID: "1", Data: "a"})
+		b.Add(Event{ID: "2", Data: "b"})
+		b.Add(Event{ID: "3", Data: "c"})
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Last-Event-ID", "1")
+
+		s, err := NewEventStream(w, r, Heartbeat(0), Replay(b))
+		expect.That(t, expect.FailNow(is.NoError(err)))
+		s.Close()
+
+		expect.That(t,
+			is.EqualTo(s.LastEventID(), "1"),
+			is.EqualTo(w.Body.String(), "id: 2\ndata: b\n\nid: 3\ndata: c\n\n"),
+		)
+	})
+
+	t.Run("heartbeat_and_disconnect", func(t *testing.T) {
+		started := make(chan struct{})
+		done := make(chan error)
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			s, err := NewEventStream(w, r, Heartbeat(time.Millisecond))
+			if err != nil {
+				done <- err
+				return
+			}
+			defer s.Close()
+
+			close(started)
+			<-s.Done()
+			done <- s.Send(Event{Data: "gone"})
+		}))
+		defer srv.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+		res, err := http.DefaultClient.Do(req)
+		expect.That(t, expect.FailNow(is.NoError(err)))
+
+		<-started
+		line, err := bufio.NewReader(res.Body).ReadString('\n')
+		expect.That(t,
+			is.NoError(err),
+			is.EqualTo(strings.TrimSpace(line), ": heartbeat"),
+		)
+
+		cancel()
+		res.Body.Close()
+
+		select {
+		case err := <-done:
+			expect.That(t, is.EqualTo(errors.Is(err, context.Canceled), true))
+		case <-time.After(5 * time.Second):
+			t.Fatal("handler did not observe disconnect")
+		}
+	})
+}
@@ -0,0 +1,210 @@
+package response
+
+import (
+	"bytes"
+	"errors"
+	"iter"
+	"net/http"
+	"time"
+
+	"github.com/halimath/httputils/bufferedresponse"
+)
+
+// DefaultStreamErrorTrailer defines the default name of the HTTP trailer used
+// to report errors that occur after a streamed response has been started.
+const DefaultStreamErrorTrailer = "Stream-Error"
+
+// DefaultStreamFlushInterval defines the default interval to flush streamed
+// responses.
+const DefaultStreamFlushInterval = 100 * time.Millisecond
+
+// jsonStreamWriter captures the headers and status code set by Options as
+// well as the configuration of a streamed JSON response.
+type jsonStreamWriter struct {
+	bufferedresponse.ResponseWriter
+
+	ndjson        bool
+	flushInterval time.Duration
+	errorTrailer  string
+}
+
+// NDJSON is an Option for [JSONStream] and [JSONStream2] that emits the
+// values as newline delimited JSON (also known as JSON Lines) with
+// content-type application/x-ndjson instead of a single JSON array. Other
+// responses ignore this Option.
+func NDJSON() Option {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		if s, ok := w.(*jsonStreamWriter); ok {
+			s.ndjson = true
+		}
+		return nil
+	}
+}
+
+// FlushInterval is an Option for [JSONStream] and [JSONStream2] that
+// configures the minimum interval between flushing the data written so far to
+// the client. An interval of 0 flushes after every value. Other responses
+// ignore this Option.
+func FlushInterval(d time.Duration) Option {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		if s, ok := w.(*jsonStreamWriter); ok {
+			s.flushInterval = d
+		}
+		return nil
+	}
+}
+
+// StreamErrorTrailer is an Option for [JSONStream] and [JSONStream2] that
+// configures the name of the HTTP trailer used to report errors that occur
+// after the response has been started. Use the empty string to disable the
+// trailer. Other responses ignore this Option.
+func StreamErrorTrailer(name string) Option {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		if s, ok := w.(*jsonStreamWriter); ok {
+			s.errorTrailer = name
+		}
+		return nil
+	}
+}
+
+// JSONStream sends all values produced by seq as a JSON array (or newline
+// delimited JSON, see [NDJSON]). See [JSONStream2] for details.
+func JSONStream[T any](w http.ResponseWriter, r *http.Request, seq iter.Seq[T], opts ...Option) error {
+	return JSONStream2(w, r, func(yield func(T, error) bool) {
+		for v := range seq {
+			if !yield(v, nil) {
+				return
+			}
+		}
+	}, opts...)
+}
+
+// JSONStream2 sends all values produced by seq as a JSON array (or newline
+// delimited JSON, see [NDJSON]). In contrast to [JSON], values are encoded
+// one by one using a [json.Encoder] and written directly to w without
+// buffering the whole response. Data written is flushed periodically (see
+// [FlushInterval]). JSONStream2 sets content-type to application/json (see
+// [Config.JSONContentType]) or application/x-ndjson (both overwritable). opts may further customize the
+// response (headers, status code).
+//
+// The response is started when the first value has been encoded. Errors
+// produced by seq or returned from encoding a value before that are returned
+// without writing anything to w, so they can be handled as usual. Once the
+// response has been started, an error truncates the response (leaving an
+// incomplete JSON array), is reported as a HTTP trailer (see
+// [StreamErrorTrailer]) and is returned. Streaming also stops when r's context
+// is done, i.e. because the client disconnected.
+//
+// Values are encoded according to r's [Config]. Pretty printing only applies
+// to JSON arrays.
+func JSONStream2[T any](w http.ResponseWriter, r *http.Request, seq iter.Seq2[T, error], opts ...Option) error {
+	s := jsonStreamWriter{
+		flushInterval: DefaultStreamFlushInterval,
+		errorTrailer:  DefaultStreamErrorTrailer,
+	}
+
+	for _, opt := range opts {
+		if err := opt(&s, r); err != nil {
+			return err
+		}
+	}
+
+	cfg := ConfigFromRequest(r)
+
+	contentType := cfg.jsonContentType()
+	if s.ndjson {
+		contentType = "application/x-ndjson"
+	}
+	SetHeader("Content-Type", contentType, false)(&s, r)
+
+	if s.errorTrailer != "" {
+		s.Header().Add("Trailer", s.errorTrailer)
+	}
+
+	rc := http.NewResponseController(w)
+	started := false
+	lastFlush := time.Now()
+
+	if s.ndjson {
+		// Each value must be written on a single line
+		cfg.PrettyPrint = false
+	}
+
+	var buf bytes.Buffer
+	enc := cfg.newJSONEncoder(&buf)
+
+	fail := func(err error) error {
+		if started && s.errorTrailer != "" {
+			w.Header().Set(s.errorTrailer, err.Error())
+		}
+		return err
+	}
+
+	write := func(data []byte) error {
+		if !started {
+			started = true
+			if err := s.WriteTo(w); err != nil {
+				return err
+			}
+		}
+
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+
+		if time.Since(lastFlush) < s.flushInterval {
+			return nil
+		}
+
+		lastFlush = time.Now()
+		if err := rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return err
+		}
+		return nil
+	}
+
+	first := true
+	for v, err := range seq {
+		if err == nil {
+			err = r.Context().Err()
+		}
+		if err != nil {
+			return fail(err)
+		}
+
+		buf.Reset()
+		if !s.ndjson {
+			if first {
+				buf.WriteByte('[')
+			} else {
+				buf.WriteByte(',')
+			}
+		}
+
+		if err := enc.Encode(v); err != nil {
+			return fail(err)
+		}
+
+		if !s.ndjson {
+			// Remove the newline added by the encoder
+			buf.Truncate(buf.Len() - 1)
+		}
+
+		if err := write(buf.Bytes()); err != nil {
+			return fail(err)
+		}
+		first = false
+	}
+
+	var end []byte
+	if !s.ndjson {
+		if first {
+			end = []byte("[]")
+		} else {
+			end = []byte("]")
+		}
+	}
+
+	s.flushInterval = 0
+	return write(end)
+}
@@ -0,0 +1,128 @@
+package response
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/halimath/expect"
+	"github.com/halimath/expect/is"
+)
+
+type streamItem struct {
+	ID int `json:"id"`
+}
+
+func streamItems(n int, err error) iter.Seq2[streamItem, error] {
+	return func(yield func(streamItem, error) bool) {
+		for i := range n {
+			if !yield(streamItem{ID: i + 1}, nil) {
+				return
+			}
+		}
+		if err != nil {
+			yield(streamItem{}, err)
+		}
+	}
+}
+
+func TestJSONStream(t *testing.T) {
+	t.Run("array", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+		err := JSONStream(w, r, slices.Values([]streamItem{{1}, {2}, {3}}), StatusCode(http.StatusAccepted))
+
+		expect.That(t,
+			is.NoError(err),
+			is.EqualTo(w.Code, http.StatusAccepted),
+			is.EqualTo(w.Header().Get("Content-Type"), "application/json"),
+			is.EqualTo(w.Body.String(), `[{"id":1},{"id":2},{"id":3}]`),
+			is.EqualTo(w.Flushed, true),
+		)
+	})
+
+	t.Run("empty_array", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+		err := JSONStream(w, r, slices.Values([]streamItem(nil)))
+
+		expect.That(t,
+			is.NoError(err),
+			is.EqualTo(w.Code, http.StatusOK),
+			is.EqualTo(w.Body.String(), `[]`),
+		)
+	})
+
+	t.Run("ndjson", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+		err := JSONStream2(w, r, streamItems(2, nil), NDJSON(), FlushInterval(0))
+
+		expect.That(t,
+			is.NoError(err),
+			is.EqualTo(w.Header().Get("Content-Type"), "application/x-ndjson"),
+			is.EqualTo(w.Body.String(), "{\"id\":1}\n{\"id\":2}\n"),
+		)
+	})
+
+	t.Run("error_before_start", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		want := errors.New("failed")
+
+		err := JSONStream2(w, r, streamItems(0, want))
+
+		expect.That(t,
+			is.Error(err, want),
+			is.EqualTo(w.Body.Len(), 0),
+			is.EqualTo(w.Header().Get("Content-Type"), ""),
+		)
+	})
+
+	t.Run("error_after_start", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		want := errors.New("failed")
+
+		err := JSONStream2(w, r, streamItems(2, want))
+		res := w.Result()
+
+		expect.That(t,
+			is.Error(err, want),
+			is.EqualTo(w.Code, http.StatusOK),
+			is.EqualTo(w.Body.String(), `[{"id":1},{"id":2}`),
+			is.EqualTo(res.Trailer.Get(DefaultStreamErrorTrailer), "failed"),
+		)
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+
+		seq := func(yield func(streamItem, error) bool) {
+			if !yield(streamItem{ID: 1}, nil) {
+				return
+			}
+			cancel()
+			if yield(streamItem{ID: 2}, nil) {
+				t.Error("expected iteration to stop")
+			}
+		}
+
+		err := JSONStream2(w, r, seq, StreamErrorTrailer("X-Error"))
+
+		expect.That(t,
+			is.Error(err, context.Canceled),
+			is.EqualTo(w.Body.String(), `[{"id":1}`),
+			is.EqualTo(w.Result().Trailer.Get("X-Error"), context.Canceled.Error()),
+		)
+	})
+}
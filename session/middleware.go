@@ -3,10 +3,10 @@ package session
 import (
 	"errors"
 	"net/http"
-	"strings"
 	"time"
 
 	"github.com/halimath/httputils"
+	"github.com/halimath/httputils/internal/secure"
 	"github.com/halimath/kvlog"
 )
 
@@ -138,7 +138,7 @@ func NewMiddleware(opts ...Option) httputils.Middleware {
 				Domain:   mw.cookie.Domain,
 				HttpOnly: true,
 				Path:     mw.cookie.Path,
-				Secure:   isSecureRequest(r),
+				Secure:   secure.IsSecureRequest(r),
 				MaxAge:   int(mw.cookie.MaxAge.Seconds()),
 				SameSite: mw.cookie.SameSite,
 			})
@@ -157,25 +157,3 @@ func NewMiddleware(opts ...Option) httputils.Middleware {
 		})
 	}
 }
-
-func isSecureRequest(r *http.Request) bool {
-	// Direct TLS connection
-	if r.TLS != nil {
-		return true
-	}
-
-	// Forwarded request header
-	if forwarded := r.Header.Get("Forwarded"); forwarded != "" {
-		if strings.Contains(forwarded, "proto=https") {
-			return true
-		}
-	}
-
-	// Legacy X-Forwarded-Proto header
-	if proto := r.Header.Get("X-Forwarded-Proto"); proto == "https" {
-		return true
-	}
-
-	// Parsed request URL
-	return r.URL.Scheme == "https"
-}
@@ -0,0 +1,27 @@
+package validate_test
+
+import (
+	"net/http"
+
+	"github.com/halimath/httputils/response"
+	"github.com/halimath/httputils/validate"
+)
+
+func Example() {
+	type createUser struct {
+		Name  string `json:"name" validate:"required,max=64"`
+		Email string `json:"email" validate:"required,email"`
+	}
+
+	http.HandleFunc("POST /users", func(w http.ResponseWriter, r *http.Request) {
+		var req createUser
+		// decode request ...
+
+		if err := validate.Struct(req); err != nil {
+			response.Problem(w, r, err.(*validate.Error).ProblemDetails())
+			return
+		}
+
+		// create user ...
+	})
+}
@@ -0,0 +1,401 @@
+// Package validate provides struct validation based on struct tags. The
+// validation result can be sent as a problem details response as defined by
+// [RFC9457].
+//
+// Rules are defined using the validate struct tag as a comma separated list:
+//
+//	type CreateUser struct {
+//		Name  string   `json:"name" validate:"required,max=64"`
+//		Email string   `json:"email" validate:"required,email"`
+//		Role  string   `json:"role" validate:"enum=admin|user"`
+//		Age   int      `json:"age" validate:"min=18"`
+//		Tags  []string `json:"tags" validate:"max=10"`
+//		Login string   `json:"login" validate:"len=8,pattern=^[a-z0-9]+$"`
+//	}
+//
+// The following rules are supported:
+//
+//   - required: the value must not be the zero value; pointers must not be
+//     nil, strings, slices and maps must not be empty
+//   - min=n, max=n: numbers must be >= or <= n; for strings (counting runes),
+//     slices and maps the length must be >= or <= n
+//   - len=n: strings (counting runes), slices and maps must have length n
+//   - pattern=re: strings must match the regular expression re. As re may
+//     contain commas, pattern must be the last rule of a tag.
+//   - enum=a|b|c: the value must be one of the given values
+//   - email: strings must contain a single email address (without a display
+//     name)
+//
+// Rules other than required are not checked for nil pointers and empty
+// strings. Nested structs, pointers to structs and slices, arrays and maps of
+// structs are validated recursively.
+//
+// Invalid tags (such as a malformed pattern) are programming errors and cause
+// a panic.
+//
+// [RFC9457]: https://www.rfc-editor.org/rfc/rfc9457
+package validate
+
+import (
+	"fmt"
+	"net/http"
+	"net/mail"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/halimath/httputils/response"
+)
+
+// TagName defines the name of the struct tag containing validation rules.
+const TagName = "validate"
+
+// FieldError describes a single validation failure. It is used as an entry of
+// [response.ProblemDetails] Errors.
+type FieldError struct {
+	// Pointer references the invalid field using a JSON pointer ([RFC6901])
+	// in URI fragment representation (i.e. #/address/street) as suggested by
+	// [RFC9457]. Field names are taken from the json struct tag.
+	//
+	// [RFC6901]: https://www.rfc-editor.org/rfc/rfc6901
+	// [RFC9457]: https://www.rfc-editor.org/rfc/rfc9457
+	Pointer string `json:"pointer"`
+
+	// Rule contains the name of the rule that failed (i.e. required).
+	Rule string `json:"rule"`
+
+	// Detail contains a human readable description of the failure.
+	Detail string `json:"detail"`
+}
+
+// Error is returned from [Struct] when validation fails. It contains all
+// failures found.
+//
+// Error provides a StatusCode, Header and ProblemDetails method so it can be
+// returned from an errmux handler which sends it as a 422 response.
+type Error struct {
+	Fields []FieldError
+}
+
+func (e *Error) Error() string {
+	var b strings.Builder
+	b.WriteString("validation failed")
+	for i, f := range e.Fields {
+		if i == 0 {
+			b.WriteString(": ")
+		} else {
+			b.WriteString("; ")
+		}
+		b.WriteString(f.Pointer)
+		b.WriteString(": ")
+		b.WriteString(f.Detail)
+	}
+	return b.String()
+}
+
+// StatusCode returns [http.StatusUnprocessableEntity].
+func (e *Error) StatusCode() int { return http.StatusUnprocessableEntity }
+
+// Header returns nil.
+func (e *Error) Header() http.Header { return nil }
+
+// ProblemDetails returns problem details with status 422 and an entry in
+// Errors for every [FieldError]. The result can be sent using
+// [response.Problem].
+func (e *Error) ProblemDetails() response.ProblemDetails {
+	errs := make([]any, len(e.Fields))
+	for i, f := range e.Fields {
+		errs[i] = f
+	}
+
+	return response.ProblemDetails{
+		Type:   "about:blank",
+		Title:  http.StatusText(http.StatusUnprocessableEntity),
+		Status: http.StatusUnprocessableEntity,
+		Detail: "The request contains invalid values.",
+		Errors: errs,
+	}
+}
+
+// Struct validates v, which must be a struct or a pointer to a struct. It
+// returns nil if v is valid and an [*Error] otherwise.
+func Struct(v any) error {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+
+	if rv.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("validate: expected struct but got %s", rv.Kind()))
+	}
+
+	var errs []FieldError
+	validateStruct(rv, "#", &errs)
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return &Error{Fields: errs}
+}
+
+// validateStruct validates all fields of the struct v appending failures to
+// errs.
+func validateStruct(v reflect.Value, pointer string, errs *[]FieldError) {
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		fv := v.Field(i)
+
+		if f.Anonymous && f.Type.Kind() == reflect.Struct && f.Tag.Get("json") == "" {
+			validateStruct(fv, pointer, errs)
+			continue
+		}
+
+		p := pointer + "/" + escapePointerToken(fieldName(f))
+
+		if tag := f.Tag.Get(TagName); tag != "" && tag != "-" {
+			for _, r := range parseRules(tag) {
+				if msg, ok := r.check(fv); !ok {
+					*errs = append(*errs, FieldError{Pointer: p, Rule: r.name, Detail: msg})
+				}
+			}
+		}
+
+		validateNested(fv, p, errs)
+	}
+}
+
+// validateNested descends into v if v is a struct, a pointer to a struct or a
+// collection of structs.
+func validateNested(v reflect.Value, pointer string, errs *[]FieldError) {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if !v.IsNil() {
+			validateNested(v.Elem(), pointer, errs)
+		}
+
+	case reflect.Struct:
+		validateStruct(v, pointer, errs)
+
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			validateNested(v.Index(i), pointer+"/"+strconv.Itoa(i), errs)
+		}
+
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			validateNested(iter.Value(), pointer+"/"+escapePointerToken(iter.Key().String()), errs)
+		}
+	}
+}
+
+// fieldName returns the name of f used in JSON pointers.
+func fieldName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return f.Name
+	}
+	return name
+}
+
+var pointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+func escapePointerToken(s string) string {
+	return pointerEscaper.Replace(s)
+}
+
+// --
+
+type rule struct {
+	name  string
+	check func(v reflect.Value) (string, bool)
+}
+
+var ruleCache sync.Map // map[string][]rule
+
+// parseRules parses tag into a list of rules. Parsed rules are cached.
+func parseRules(tag string) []rule {
+	if rules, ok := ruleCache.Load(tag); ok {
+		return rules.([]rule)
+	}
+
+	var rules []rule
+	rest := tag
+	for rest != "" {
+		var part string
+		if strings.HasPrefix(rest, "pattern=") {
+			part, rest = rest, ""
+		} else {
+			part, rest, _ = strings.Cut(rest, ",")
+		}
+
+		name, arg, _ := strings.Cut(strings.TrimSpace(part), "=")
+		if name == "" {
+			continue
+		}
+		rules = append(rules, newRule(name, arg))
+	}
+
+	ruleCache.Store(tag, rules)
+	return rules
+}
+
+func newRule(name, arg string) rule {
+	r := rule{name: name}
+
+	switch name {
+	case "required":
+		r.check = checkRequired
+
+	case "min", "max", "len":
+		n, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			panic(fmt.Sprintf("validate: invalid argument for rule %s: %q", name, arg))
+		}
+		r.check = optional(bounds(name, n))
+
+	case "pattern":
+		re, err := regexp.Compile(arg)
+		if err != nil {
+			panic(fmt.Sprintf("validate: invalid pattern %q: %v", arg, err))
+		}
+		r.check = optional(func(v reflect.Value) (string, bool) {
+			if v.Kind() != reflect.String {
+				panic(fmt.Sprintf("validate: rule pattern not supported for %s", v.Type()))
+			}
+			if re.MatchString(v.String()) {
+				return "", true
+			}
+			return fmt.Sprintf("must match pattern %s", arg), false
+		})
+
+	case "enum":
+		values := strings.Split(arg, "|")
+		r.check = optional(func(v reflect.Value) (string, bool) {
+			s := fmt.Sprint(v.Interface())
+			for _, val := range values {
+				if s == val {
+					return "", true
+				}
+			}
+			return fmt.Sprintf("must be one of %s", strings.Join(values, ", ")), false
+		})
+
+	case "email":
+		r.check = optional(func(v reflect.Value) (string, bool) {
+			if v.Kind() != reflect.String {
+				panic(fmt.Sprintf("validate: rule email not supported for %s", v.Type()))
+			}
+			addr, err := mail.ParseAddress(v.String())
+			if err == nil && addr.Name == "" && addr.Address == v.String() {
+				return "", true
+			}
+			return "must be a valid email address", false
+		})
+
+	default:
+		panic(fmt.Sprintf("validate: unknown rule: %s", name))
+	}
+
+	return r
+}
+
+func checkRequired(v reflect.Value) (string, bool) {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		if v.Len() > 0 {
+			return "", true
+		}
+	default:
+		if !v.IsZero() {
+			return "", true
+		}
+	}
+	return "is required", false
+}
+
+// optional wraps check to skip nil pointers and empty strings and to
+// dereference non-nil pointers.
+func optional(check func(v reflect.Value) (string, bool)) func(v reflect.Value) (string, bool) {
+	return func(v reflect.Value) (string, bool) {
+		for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+			if v.IsNil() {
+				return "", true
+			}
+			v = v.Elem()
+		}
+
+		if v.Kind() == reflect.String && v.Len() == 0 {
+			return "", true
+		}
+
+		return check(v)
+	}
+}
+
+// bounds creates a check for the min, max and len rules.
+func bounds(name string, n float64) func(v reflect.Value) (string, bool) {
+	return func(v reflect.Value) (string, bool) {
+		var val float64
+		isLength := true
+
+		switch v.Kind() {
+		case reflect.String:
+			val = float64(utf8.RuneCountInString(v.String()))
+		case reflect.Slice, reflect.Array, reflect.Map:
+			val = float64(v.Len())
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			val, isLength = float64(v.Int()), false
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			val, isLength = float64(v.Uint()), false
+		case reflect.Float32, reflect.Float64:
+			val, isLength = v.Float(), false
+		default:
+			panic(fmt.Sprintf("validate: rule %s not supported for %s", name, v.Type()))
+		}
+
+		arg := strconv.FormatFloat(n, 'f', -1, 64)
+
+		switch name {
+		case "min":
+			if val >= n {
+				return "", true
+			}
+			if isLength {
+				return fmt.Sprintf("must have a length of at least %s", arg), false
+			}
+			return fmt.Sprintf("must be at least %s", arg), false
+
+		case "max":
+			if val <= n {
+				return "", true
+			}
+			if isLength {
+				return fmt.Sprintf("must have a length of at most %s", arg), false
+			}
+			return fmt.Sprintf("must be at most %s", arg), false
+
+		default: // len
+			if val == n {
+				return "", true
+			}
+			return fmt.Sprintf("must have a length of %s", arg), false
+		}
+	}
+}
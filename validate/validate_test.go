@@ -0,0 +1,104 @@
+package validate
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/halimath/expect"
+	"github.com/halimath/expect/is"
+)
+
+type address struct {
+	Street string `json:"street" validate:"required"`
+	Zip    string `json:"zip" validate:"len=5,pattern=^[0-9]+$"`
+}
+
+type user struct {
+	Name      string             `json:"name" validate:"required,max=8"`
+	Email     string             `json:"email" validate:"email"`
+	Role      string             `json:"role,omitempty" validate:"enum=admin|user"`
+	Age       int                `json:"age" validate:"min=18,max=120"`
+	Nick      *string            `json:"nick" validate:"min=3"`
+	Tags      []string           `json:"tags" validate:"required,max=2"`
+	Address   address            `json:"address"`
+	Others    []address          `json:"others"`
+	Labels    map[string]address `json:"labels"`
+	NotJSON   string             `json:"-" validate:"required"`
+	unchecked string             `validate:"required"`
+}
+
+func TestStruct_valid(t *testing.T) {
+	u := user{
+		Name:    "jdoe",
+		Email:   "jdoe@example.com",
+		Role:    "admin",
+		Age:     42,
+		Tags:    []string{"a"},
+		Address: address{Street: "Main St.", Zip: "12345"},
+		NotJSON: "x",
+	}
+
+	expect.That(t,
+		is.NoError(Struct(u)),
+		is.NoError(Struct(&u)),
+	)
+}
+
+func TestStruct_invalid(t *testing.T) {
+	nick := "jd"
+	u := user{
+		Name:    "john.doe.jr",
+		Email:   "John <jdoe@example.com>",
+		Role:    "root",
+		Age:     17,
+		Nick:    &nick,
+		Tags:    []string{"a", "b", "c"},
+		Address: address{Zip: "1234a"},
+		Others:  []address{{Street: "a", Zip: "12345"}, {Zip: "1"}},
+		Labels:  map[string]address{"a/b": {Street: "b", Zip: "x"}},
+	}
+
+	err := Struct(u)
+
+	var verr *Error
+	expect.That(t, expect.FailNow(is.EqualTo(errors.As(err, &verr), true)))
+
+	expect.That(t, is.DeepEqualTo(verr.Fields, []FieldError{
+		{Pointer: "#/name", Rule: "max", Detail: "must have a length of at most 8"},
+		{Pointer: "#/email", Rule: "email", Detail: "must be a valid email address"},
+		{Pointer: "#/role", Rule: "enum", Detail: "must be one of admin, user"},
+		{Pointer: "#/age", Rule: "min", Detail: "must be at least 18"},
+		{Pointer: "#/nick", Rule: "min", Detail: "must have a length of at least 3"},
+		{Pointer: "#/tags", Rule: "max", Detail: "must have a length of at most 2"},
+		{Pointer: "#/address/street", Rule: "required", Detail: "is required"},
+		{Pointer: "#/address/zip", Rule: "pattern", Detail: "must match pattern ^[0-9]+$"},
+		{Pointer: "#/others/1/street", Rule: "required", Detail: "is required"},
+		{Pointer: "#/others/1/zip", Rule: "len", Detail: "must have a length of 5"},
+		{Pointer: "#/labels/a~1b/zip", Rule: "len", Detail: "must have a length of 5"},
+		{Pointer: "#/labels/a~1b/zip", Rule: "pattern", Detail: "must match pattern ^[0-9]+$"},
+		{Pointer: "#/NotJSON", Rule: "required", Detail: "is required"},
+	}))
+}
+
+func TestError_ProblemDetails(t *testing.T) {
+	err := &Error{Fields: []FieldError{{Pointer: "#/name", Rule: "required", Detail: "is required"}}}
+
+	data, jsonErr := json.Marshal(err.ProblemDetails())
+
+	expect.That(t,
+		is.NoError(jsonErr),
+		is.EqualTo(err.StatusCode(), 422),
+		is.EqualTo(string(data), `{"type":"about:blank","title":"Unprocessable Entity","status":422,"detail":"The request contains invalid values.","errors":[{"pointer":"#/name","rule":"required","detail":"is required"}]}`),
+	)
+}
+
+func TestStruct_invalidTag(t *testing.T) {
+	defer func() {
+		expect.That(t, is.EqualTo(recover() != nil, true))
+	}()
+
+	Struct(struct {
+		A string `validate:"unknown"`
+	}{})
+}